@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Update modes a VerticalPodAutoscaler accepts
+var vpaUpdateModes = []string{"Off", "Initial", "Recreate", "InPlaceOrRecreate", "Auto"}
+
+// verticalAutoscalingPolicy is how freely an importance level lets the
+// VerticalPodAutoscaler move the decided resources.
+type verticalAutoscalingPolicy struct {
+	UpdateMode        string `yaml:"updateMode"`        // "Off" only recommends, "Initial" applies to new pods, "Auto" evicts pods to apply
+	MinAllowedPercent int    `yaml:"minAllowedPercent"` // Lowest recommendation, as a percentage of the decided requests
+	MaxAllowedPercent int    `yaml:"maxAllowedPercent"` // Highest recommendation, as a percentage of the decided requests
+}
+
+// Policy of importance levels the platform config gives none: recommend
+// within half and double the decided requests, change nothing
+var defaultVerticalAutoscalingPolicy = verticalAutoscalingPolicy{UpdateMode: "Off", MinAllowedPercent: 50, MaxAllowedPercent: 200}
+
+// AutoscalingSpec represents the decided vertical autoscaling, empty unless
+// the spec asks for it.
+type AutoscalingSpec struct {
+	Policy verticalAutoscalingPolicy
+}
+
+// Checks the policy's values
+func (policy verticalAutoscalingPolicy) validate() error {
+	if !slices.Contains(vpaUpdateModes, policy.UpdateMode) {
+		return fmt.Errorf("updateMode must be one of %v, got %q", vpaUpdateModes, policy.UpdateMode)
+	}
+	if policy.MinAllowedPercent <= 0 || policy.MinAllowedPercent > 100 {
+		return fmt.Errorf("minAllowedPercent must be between 1 and 100, got %d", policy.MinAllowedPercent)
+	}
+	if policy.MaxAllowedPercent < 100 {
+		return fmt.Errorf("maxAllowedPercent must be at least 100, got %d", policy.MaxAllowedPercent)
+	}
+	return nil
+}
+
+// Decides how the VerticalPodAutoscaler may change the app's resources
+func (config *ConfigSpec) decideAutoscaling(resultChan chan<- TimedResult) {
+	startTime := time.Now()
+
+	var autoscaling AutoscalingSpec
+	var err error
+	if config.VerticalAutoscaling {
+		var platform platformConfig
+		platform, err = loadPlatformConfig()
+		policy, ok := platform.VerticalAutoscaling[config.ImportanceLevel]
+		if !ok {
+			policy = defaultVerticalAutoscalingPolicy
+		}
+		autoscaling.Policy = policy
+	}
+	resultChan <- TimedResult{
+		Name:            "autoscaling",
+		AutoscalingSpec: autoscaling,
+		Duration:        time.Since(startTime),
+		Error:           err,
+	}
+}
+
+// Generates the VerticalPodAutoscaler of the app and its sidecars, bounded
+// around their decided requests
+func generateVerticalPodAutoscaler(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	compute := timedResults["compute"].ComputeSpec
+	policy := timedResults["autoscaling"].AutoscalingSpec.Policy
+
+	// Init containers are left alone, they have finished by the time there
+	// is usage to recommend from
+	containers := []ContainerResources{{ContainerSpec: ContainerSpec{Name: config.AppName + "-container"}, CPU: compute.CPU, Memory: compute.Memory}}
+	for _, container := range compute.Containers {
+		if container.Role == "sidecar" {
+			containers = append(containers, container)
+		}
+	}
+
+	var sb strings.Builder
+	for _, container := range containers {
+		cpu := container.CPU * 0.8
+		memoryMi, _ := parseMemoryMi(container.Memory)
+		minimum, maximum := float64(policy.MinAllowedPercent)/100, float64(policy.MaxAllowedPercent)/100
+		sb.WriteString(fmt.Sprintf(`      - containerName: %s
+        controlledResources: ["cpu", "memory"]
+        minAllowed:
+          cpu: "%.2f"
+          memory: "%dMi"
+        maxAllowed:
+          cpu: "%.2f"
+          memory: "%dMi"
+`, container.Name,
+			math.Max(0.01, math.Floor(cpu*minimum*100)/100), int(math.Floor(memoryMi*minimum)),
+			ceilCores(cpu*maximum), int(math.Ceil(memoryMi*maximum))))
+	}
+
+	return fmt.Sprintf(`
+apiVersion: autoscaling.k8s.io/v1
+kind: VerticalPodAutoscaler
+metadata:
+  name: %s-vpa
+spec:
+  targetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: %s-deployment
+  updatePolicy:
+    updateMode: %q
+  resourcePolicy:
+    containerPolicies:
+%s`, config.AppName, config.AppName, policy.UpdateMode, sb.String())
+}
+
+// Describes the vertical autoscaling for summaries
+func (autoscaling AutoscalingSpec) String() string {
+	policy := autoscaling.Policy
+	return fmt.Sprintf("VerticalPodAutoscaler in %s mode, between %d%% and %d%% of the decided requests",
+		policy.UpdateMode, policy.MinAllowedPercent, policy.MaxAllowedPercent)
+}
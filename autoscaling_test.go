@@ -0,0 +1,80 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateVerticalPodAutoscaler(t *testing.T) {
+	config := &ConfigSpec{AppName: "shop"}
+	timedResults := map[string]TimedResult{
+		"compute": {ComputeSpec: ComputeSpec{CPU: 1, Memory: "512Mi", Containers: []ContainerResources{
+			{ContainerSpec: ContainerSpec{Name: "proxy", Role: "sidecar"}, CPU: 0.2, Memory: "103Mi"},
+			{ContainerSpec: ContainerSpec{Name: "migrate", Role: "init"}, CPU: 2, Memory: "1Gi"},
+			{ContainerSpec: ContainerSpec{Name: "agent", Role: "sidecar"}, CPU: 0.01, Memory: "16Mi"},
+		}}},
+		"autoscaling": {AutoscalingSpec: AutoscalingSpec{Policy: verticalAutoscalingPolicy{UpdateMode: "Auto", MinAllowedPercent: 50, MaxAllowedPercent: 200}}},
+	}
+
+	type resources struct {
+		CPU    string `yaml:"cpu"`
+		Memory string `yaml:"memory"`
+	}
+	type containerPolicy struct {
+		ContainerName string    `yaml:"containerName"`
+		MinAllowed    resources `yaml:"minAllowed"`
+		MaxAllowed    resources `yaml:"maxAllowed"`
+	}
+	var vpa struct {
+		Spec struct {
+			TargetRef struct {
+				Name string `yaml:"name"`
+			} `yaml:"targetRef"`
+			UpdatePolicy struct {
+				UpdateMode string `yaml:"updateMode"`
+			} `yaml:"updatePolicy"`
+			ResourcePolicy struct {
+				ContainerPolicies []containerPolicy `yaml:"containerPolicies"`
+			} `yaml:"resourcePolicy"`
+		} `yaml:"spec"`
+	}
+	manifest := generateVerticalPodAutoscaler(config, timedResults)
+	if err := yaml.Unmarshal([]byte(manifest), &vpa); err != nil {
+		t.Fatalf("generated VerticalPodAutoscaler does not parse: %v\n%s", err, manifest)
+	}
+
+	if vpa.Spec.TargetRef.Name != "shop-deployment" || vpa.Spec.UpdatePolicy.UpdateMode != "Auto" {
+		t.Errorf("target %s in %s mode, want shop-deployment in Auto mode", vpa.Spec.TargetRef.Name, vpa.Spec.UpdatePolicy.UpdateMode)
+	}
+	// Bands are taken around the requests, 80% of the limits, and init
+	// containers are left out
+	want := []containerPolicy{
+		{"shop-container", resources{"0.40", "256Mi"}, resources{"1.60", "1024Mi"}},
+		{"proxy", resources{"0.08", "51Mi"}, resources{"0.32", "206Mi"}},
+		{"agent", resources{"0.01", "8Mi"}, resources{"0.02", "32Mi"}}, // The minimum never drops to zero cores
+	}
+	if got := vpa.Spec.ResourcePolicy.ContainerPolicies; !slices.Equal(got, want) {
+		t.Errorf("container policies = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerticalAutoscalingPolicyValidate(t *testing.T) {
+	tests := []struct {
+		policy verticalAutoscalingPolicy
+		valid  bool
+	}{
+		{defaultVerticalAutoscalingPolicy, true},
+		{verticalAutoscalingPolicy{UpdateMode: "InPlaceOrRecreate", MinAllowedPercent: 100, MaxAllowedPercent: 100}, true},
+		{verticalAutoscalingPolicy{UpdateMode: "auto", MinAllowedPercent: 50, MaxAllowedPercent: 200}, false},
+		{verticalAutoscalingPolicy{UpdateMode: "Off", MinAllowedPercent: 0, MaxAllowedPercent: 200}, false},
+		{verticalAutoscalingPolicy{UpdateMode: "Off", MinAllowedPercent: 120, MaxAllowedPercent: 200}, false},
+		{verticalAutoscalingPolicy{UpdateMode: "Off", MinAllowedPercent: 50, MaxAllowedPercent: 90}, false},
+	}
+	for _, test := range tests {
+		if err := test.policy.validate(); (err == nil) != test.valid {
+			t.Errorf("validate(%+v) error = %v, want valid %v", test.policy, err, test.valid)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// How strictly a scheduling constraint is enforced
+const (
+	scheduleConstraintPreferred = "preferred" // Best effort, pods still schedule when it cannot be met
+	scheduleConstraintRequired  = "required"  // Pods stay pending rather than break it
+)
+
+// availabilityPolicy is what an importance level guarantees about the
+// app's availability during disruptions and failures.
+type availabilityPolicy struct {
+	MinReplicas    int    `yaml:"minReplicas"`
+	MaxUnavailable string `yaml:"maxUnavailable"` // PodDisruptionBudget limit, e.g. "1" or "25%", none if empty
+	ZoneSpread     string `yaml:"zoneSpread"`     // Spread across zones: "", "preferred" or "required"
+	NodeSpread     string `yaml:"nodeSpread"`     // Spread across nodes: "", "preferred" or "required"
+	AntiAffinity   string `yaml:"antiAffinity"`   // Keep replicas off each other's node: "", "preferred" or "required"
+}
+
+// AvailabilitySpec represents the decided availability guarantees.
+type AvailabilitySpec struct {
+	ImportanceLevel string
+	Policy          availabilityPolicy
+}
+
+var maxUnavailablePattern = regexp.MustCompile(`^[0-9]+%?$`)
+
+// Checks the policy's values
+func (policy availabilityPolicy) validate() error {
+	if policy.MinReplicas < 0 {
+		return fmt.Errorf("minReplicas must not be negative")
+	}
+	if policy.MaxUnavailable != "" && !maxUnavailablePattern.MatchString(policy.MaxUnavailable) {
+		return fmt.Errorf("maxUnavailable must be a number or a percentage, got %q", policy.MaxUnavailable)
+	}
+	strengths := []string{"", scheduleConstraintPreferred, scheduleConstraintRequired}
+	for _, field := range []struct{ name, value string }{
+		{"zoneSpread", policy.ZoneSpread},
+		{"nodeSpread", policy.NodeSpread},
+		{"antiAffinity", policy.AntiAffinity},
+	} {
+		if !slices.Contains(strengths, field.value) {
+			return fmt.Errorf("%s must be %q or %q, got %q", field.name, scheduleConstraintPreferred, scheduleConstraintRequired, field.value)
+		}
+	}
+	return nil
+}
+
+// Availability policy of the spec's importance level from the platform config
+func (config *ConfigSpec) availabilityPolicy() (availabilityPolicy, error) {
+	platform, err := loadPlatformConfig()
+	if err != nil {
+		return availabilityPolicy{}, err
+	}
+	return platform.Availability[config.ImportanceLevel], nil
+}
+
+// Replicas the app runs: the importance level's minimum, or more when its
+// latency SLO needs them
+func (config *ConfigSpec) replicaCount() (int, error) {
+	policy, err := config.availabilityPolicy()
+	if err != nil {
+		return 0, err
+	}
+	replicas := max(1, policy.MinReplicas)
+	if config.CPUTimePerRequestMs > 0 {
+		sized, err := config.sizeForLatency(replicas)
+		if err != nil {
+			return 0, err
+		}
+		replicas = sized.Replicas
+	}
+	return replicas, nil
+}
+
+// Decides the disruption budget and scheduling constraints of the app
+func (config *ConfigSpec) decideAvailability(resultChan chan<- TimedResult) {
+	startTime := time.Now()
+
+	policy, err := config.availabilityPolicy()
+	resultChan <- TimedResult{
+		Name:             "availability",
+		AvailabilitySpec: AvailabilitySpec{ImportanceLevel: config.ImportanceLevel, Policy: policy},
+		Duration:         time.Since(startTime),
+		Error:            err,
+	}
+}
+
+// Renders the pod spec fields that spread the replicas, indented for the
+// Deployment's pod template
+func schedulingString(appName string, policy availabilityPolicy) string {
+	var sb strings.Builder
+
+	spreads := []struct{ strength, topologyKey string }{
+		{policy.ZoneSpread, "topology.kubernetes.io/zone"},
+		{policy.NodeSpread, "kubernetes.io/hostname"},
+	}
+	header := "      topologySpreadConstraints:\n"
+	for _, spread := range spreads {
+		if spread.strength == "" {
+			continue
+		}
+		whenUnsatisfiable := "ScheduleAnyway"
+		if spread.strength == scheduleConstraintRequired {
+			whenUnsatisfiable = "DoNotSchedule"
+		}
+		sb.WriteString(header)
+		header = ""
+		sb.WriteString(fmt.Sprintf(`        - maxSkew: 1
+          topologyKey: %s
+          whenUnsatisfiable: %s
+          labelSelector:
+            matchLabels:
+              app: %s
+`, spread.topologyKey, whenUnsatisfiable, appName))
+	}
+
+	switch policy.AntiAffinity {
+	case scheduleConstraintPreferred:
+		sb.WriteString(fmt.Sprintf(`      affinity:
+        podAntiAffinity:
+          preferredDuringSchedulingIgnoredDuringExecution:
+            - weight: 100
+              podAffinityTerm:
+                topologyKey: kubernetes.io/hostname
+                labelSelector:
+                  matchLabels:
+                    app: %s
+`, appName))
+	case scheduleConstraintRequired:
+		sb.WriteString(fmt.Sprintf(`      affinity:
+        podAntiAffinity:
+          requiredDuringSchedulingIgnoredDuringExecution:
+            - topologyKey: kubernetes.io/hostname
+              labelSelector:
+                matchLabels:
+                  app: %s
+`, appName))
+	}
+	return sb.String()
+}
+
+// Generates the PodDisruptionBudget limiting voluntary evictions
+func generatePodDisruptionBudget(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	policy := timedResults["availability"].AvailabilitySpec.Policy
+	return fmt.Sprintf(`
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: %s-pdb
+spec:
+  maxUnavailable: %s
+  selector:
+    matchLabels:
+      app: %s
+`, config.AppName, policy.MaxUnavailable, config.AppName)
+}
+
+// Describes the guarantees for summaries
+func (availability AvailabilitySpec) String() string {
+	policy := availability.Policy
+	var guarantees []string
+	if policy.MinReplicas > 0 {
+		guarantees = append(guarantees, fmt.Sprintf("at least %d replicas", policy.MinReplicas))
+	}
+	if policy.MaxUnavailable != "" {
+		guarantees = append(guarantees, fmt.Sprintf("at most %s unavailable during disruptions", policy.MaxUnavailable))
+	}
+	for _, constraint := range []struct{ strength, name string }{
+		{policy.ZoneSpread, "zone spreading"},
+		{policy.NodeSpread, "node spreading"},
+		{policy.AntiAffinity, "anti-affinity"},
+	} {
+		if constraint.strength != "" {
+			guarantees = append(guarantees, fmt.Sprintf("%s %s", constraint.strength, constraint.name))
+		}
+	}
+	if len(guarantees) == 0 {
+		return fmt.Sprintf("No guarantees for %s importance", availability.ImportanceLevel)
+	}
+	return strings.Join(guarantees, ", ")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Directory the generated manifests are written to
+const manifestDir = "k8s"
+
+// writeMode decides what happens to generated documents whose file already
+// exists with different content.
+type writeMode int
+
+const (
+	writeModeOverwrite writeMode = iota // Replace the existing file
+	writeModeAlongside                  // Keep the existing file, write <name>.new.yaml next to it
+	writeModeMerge                      // Patch the decisions into the existing file
+)
+
+// manifestConflict is a generated document whose target file already exists
+// with different content, most likely because it was edited by hand.
+type manifestConflict struct {
+	Doc      manifestDocument
+	Path     string
+	Existing string
+	Diff     string        // Unified diff from the existing file to the generated one
+	Changes  []fieldChange // Field-level changes, empty if the existing file cannot be parsed
+}
+
+// Finds the generated documents that would clobber different existing files
+func findManifestConflicts(docs []manifestDocument) ([]manifestConflict, error) {
+	var conflicts []manifestConflict
+	for _, doc := range docs {
+		path := filepath.Join(manifestDir, doc.File)
+		existing, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading existing manifest %s: %v", path, err)
+		}
+		if string(existing) == doc.Content {
+			continue
+		}
+
+		// A hand-edited file may no longer parse; the line diff still helps
+		changes, _ := semanticChanges(string(existing), doc.Content)
+		conflicts = append(conflicts, manifestConflict{
+			Doc:      doc,
+			Path:     path,
+			Existing: string(existing),
+			Diff:     unifiedDiff(path, "generated", string(existing), doc.Content),
+			Changes:  changes,
+		})
+	}
+	return conflicts, nil
+}
+
+// Writes the documents into the k8s directory and returns the written paths
+// and, when merging, the files whose documents could not be merged. Existing
+// files are only replaced when mode is writeModeOverwrite.
+func writeManifests(docs []manifestDocument, mode writeMode) ([]string, []string, error) {
+	// Create the "k8s" directory if it doesn't exist
+	err := os.MkdirAll(manifestDir, 0755)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating k8s directory: %v", err)
+	}
+
+	var paths, notMerged []string
+	for _, doc := range docs {
+		outputPath := filepath.Join(manifestDir, doc.File)
+		existing, err := os.ReadFile(outputPath)
+		if mode != writeModeOverwrite && err == nil && string(existing) != doc.Content {
+			if mode == writeModeMerge {
+				report, err := mergeIntoFile(outputPath, []manifestDocument{doc})
+				if err != nil {
+					return paths, notMerged, err
+				}
+				// The file is left as it was for a skipped document
+				if len(report.Skipped) > 0 {
+					for _, skipped := range report.Skipped {
+						notMerged = append(notMerged, fmt.Sprintf("`%s`: %s", outputPath, skipped))
+					}
+					continue
+				}
+				paths = append(paths, outputPath)
+				continue
+			}
+			outputPath = alongsidePath(outputPath)
+		}
+
+		// Write the manifest to the specified file
+		err = os.WriteFile(outputPath, []byte(doc.Content), 0644)
+		if err != nil {
+			return paths, notMerged, fmt.Errorf("error writing Kubernetes manifest: %v", err)
+		}
+		paths = append(paths, outputPath)
+	}
+	return paths, notMerged, nil
+}
+
+// Path used when writing next to an existing file, e.g. app-deployment.new.yaml
+func alongsidePath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".new" + ext
+}
+
+// Describes the conflicts as Markdown: the field changes followed by the diff
+func conflictsMarkdown(conflicts []manifestConflict, includeDiff bool) string {
+	var sb strings.Builder
+	sb.WriteString("# Existing Manifests Differ\n\n")
+	for _, conflict := range conflicts {
+		sb.WriteString(fmt.Sprintf("## `%s`\n\n", conflict.Path))
+		if len(conflict.Changes) == 0 {
+			sb.WriteString("- No field changes, only formatting or comments differ\n")
+		}
+		for _, change := range conflict.Changes {
+			sb.WriteString(fmt.Sprintf("- `%s`: %s ⇒ %s\n", change.Path, change.Old, change.New))
+		}
+		if includeDiff {
+			sb.WriteString("\n```diff\n" + conflict.Diff + "```\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Handles the overwrite prompt shown when existing manifests differ
+func (m model) updateConfirmOverwrite(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "pgup":
+		m.preview.HalfPageUp()
+	case "pgdown":
+		m.preview.HalfPageDown()
+	case "o":
+		cmd = m.finishWrite(writeModeOverwrite)
+	case "w":
+		cmd = m.finishWrite(writeModeAlongside)
+	case "m":
+		cmd = m.finishWrite(writeModeMerge)
+	case "a", "esc":
+		m.inputState = inputStateDone
+		m.output = "# Aborted\n\nNo manifests were written, existing files are unchanged.\n"
+		cmd = tea.Quit
+	}
+	return m, cmd
+}
+
+// Loads the unified diffs of all conflicts into the scrollable viewport
+func (m *model) showConflictDiff() {
+	var diffs strings.Builder
+	for _, conflict := range m.conflicts {
+		diffs.WriteString(conflict.Diff)
+	}
+
+	m.preview.Width = max(20, m.width-4)
+	m.preview.Height = max(5, m.height/2)
+	m.preview.SetContent(highlight(diffs.String(), "diff"))
+	m.preview.GotoTop()
+}
+
+// Renders the field changes, the diff and the available choices
+func (m model) confirmOverwriteView() string {
+	summary, err := renderMarkdown(conflictsMarkdown(m.conflicts, false), max(40, m.width-4))
+	if err != nil {
+		summary = conflictsMarkdown(m.conflicts, false)
+	}
+
+	return summary + previewBorderStyle.Render(m.preview.View()) +
+		"\nPress o to overwrite, m to merge into the existing files, w to write alongside as *.new.yaml, a to abort, PgUp/PgDn to scroll the diff.\n"
+}
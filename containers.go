@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Roles an extra container can take: a sidecar runs beside the app for the
+// pod's lifetime, an init container runs to completion before it starts
+var containerRoles = []string{"sidecar", "init"}
+
+// How summaries name the roles
+var containerRoleTitles = map[string]string{"sidecar": "Sidecar", "init": "Init container"}
+
+// Names of containers and namespaces are DNS labels
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ContainerSpec declares a container the pod runs besides the app, such as a
+// proxy sidecar or a migration init container.
+type ContainerSpec struct {
+	Name    string   `yaml:"name"`
+	Role    string   `yaml:"role"`              // "sidecar" or "init"
+	Image   string   `yaml:"image,omitempty"`   // The profile's image if unset
+	Command []string `yaml:"command,omitempty"` // The image's entrypoint if unset
+	Profile string   `yaml:"profile,omitempty"` // Sizes the container from the sidecar catalog
+	Share   float64  `yaml:"share,omitempty"`   // Fraction of the pod's budget, e.g. 0.2
+}
+
+// ContainerResources is an extra container with its decided resources.
+type ContainerResources struct {
+	ContainerSpec
+	CPU    float64 // in cores
+	Memory string  // in Mi
+}
+
+// sidecarProfile is what a well-known sidecar needs, independent of the app
+// it runs beside.
+type sidecarProfile struct {
+	Name          string  `yaml:"name"`
+	Image         string  `yaml:"image"`
+	CPU           float64 `yaml:"cpu"`           // Cores when idle
+	CPUPer1000RPS float64 `yaml:"cpuPer1000RPS"` // Cores added per 1000 requests per second passing through
+	MemoryMi      int     `yaml:"memoryMi"`
+}
+
+// sidecarCatalog lists the sidecar profiles containers can be sized from.
+type sidecarCatalog struct {
+	Sidecars []sidecarProfile `yaml:"sidecars"`
+}
+
+// Catalog used when the cluster does not define one. Proxies scale with the
+// requests they forward, the others barely with load.
+var defaultSidecarCatalog = sidecarCatalog{
+	Sidecars: []sidecarProfile{
+		{Name: "envoy", Image: "envoyproxy/envoy:v1.31.2", CPU: 0.05, CPUPer1000RPS: 0.2, MemoryMi: 64},
+		{Name: "linkerd-proxy", Image: "cr.l5d.io/linkerd/proxy:stable-2.14.10", CPU: 0.02, CPUPer1000RPS: 0.1, MemoryMi: 32},
+		{Name: "cloud-sql-proxy", Image: "gcr.io/cloud-sql-connectors/cloud-sql-proxy:2.14.0", CPU: 0.1, MemoryMi: 128},
+		{Name: "fluent-bit", Image: "cr.fluentbit.io/fluent/fluent-bit:3.1.9", CPU: 0.05, CPUPer1000RPS: 0.05, MemoryMi: 64},
+	},
+}
+
+// Path of the user-defined sidecar catalog
+func sidecarCatalogPath() string {
+	return filepath.Join(stateDir, "sidecars.yaml")
+}
+
+// Loads the sidecar catalog, falling back to the default one
+func loadSidecarCatalog() (sidecarCatalog, error) {
+	data, err := os.ReadFile(sidecarCatalogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultSidecarCatalog, nil
+	}
+	if err != nil {
+		return sidecarCatalog{}, fmt.Errorf("error reading sidecar catalog: %v", err)
+	}
+
+	var catalog sidecarCatalog
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&catalog); err != nil {
+		return sidecarCatalog{}, fmt.Errorf("error parsing %s: %v", sidecarCatalogPath(), err)
+	}
+	for _, profile := range catalog.Sidecars {
+		if profile.Name == "" || profile.CPU <= 0 || profile.MemoryMi <= 0 || profile.CPUPer1000RPS < 0 {
+			return sidecarCatalog{}, fmt.Errorf("sidecar profile %q needs a name, a positive cpu and memoryMi", profile.Name)
+		}
+	}
+	return catalog, nil
+}
+
+// Looks up a profile by name
+func (catalog sidecarCatalog) profile(name string) (sidecarProfile, error) {
+	names := make([]string, len(catalog.Sidecars))
+	for i, profile := range catalog.Sidecars {
+		if profile.Name == name {
+			return profile, nil
+		}
+		names[i] = profile.Name
+	}
+	return sidecarProfile{}, fmt.Errorf("unknown sidecar profile %q, the catalog has %s", name, strings.Join(names, ", "))
+}
+
+// Checks the declared extra containers
+func validateContainers(appName string, containers []ContainerSpec) error {
+	seen := map[string]bool{appName + "-container": true}
+	var sidecarShares float64
+	for _, container := range containers {
+		if !dnsLabelPattern.MatchString(container.Name) || len(container.Name) > 63 {
+			return fmt.Errorf("container name %q must be a DNS label", container.Name)
+		}
+		if seen[container.Name] {
+			return fmt.Errorf("container %s is declared twice or clashes with the app container", container.Name)
+		}
+		seen[container.Name] = true
+
+		if !slices.Contains(containerRoles, container.Role) {
+			return fmt.Errorf("container %s: role must be one of %v, got %q", container.Name, containerRoles, container.Role)
+		}
+		if container.Share < 0 || container.Share >= 1 {
+			return fmt.Errorf("container %s: share must be between 0 and 1, got %g", container.Name, container.Share)
+		}
+		if container.Share > 0 && container.Profile != "" {
+			return fmt.Errorf("container %s: share and profile exclude each other", container.Name)
+		}
+		if container.Image == "" && container.Profile == "" {
+			return fmt.Errorf("container %s needs an image", container.Name)
+		}
+		if container.Image != "" {
+			repository, tag, digest := parseImageReference(container.Image)
+			if !imageRepositoryPattern.MatchString(repository) ||
+				(tag != "" && !imageTagPattern.MatchString(tag)) ||
+				(digest != "" && !imageDigestPattern.MatchString(digest)) {
+				return fmt.Errorf("container %s: %q is not a valid image reference", container.Name, container.Image)
+			}
+		}
+		if container.Role == "sidecar" {
+			if container.Share == 0 && container.Profile == "" {
+				return fmt.Errorf("sidecar %s needs a share of the pod budget or a profile", container.Name)
+			}
+			sidecarShares += container.Share
+		}
+	}
+	if sidecarShares >= 1 {
+		return fmt.Errorf("sidecar shares add up to %g, leaving nothing for the app", sidecarShares)
+	}
+	return nil
+}
+
+// Sizes the extra containers from the pod's budget and returns them with the
+// share of the budget left for the app. Sidecars with a share take it from
+// the budget, profiled ones come on top sized for the load per replica. Init
+// containers run alone before the app, so they get the whole budget unless
+// they declare less.
+func (config *ConfigSpec) sizeContainers(cpu, memoryMi float64, replicas int) ([]ContainerResources, float64, error) {
+	if len(config.Containers) == 0 {
+		return nil, 1, nil
+	}
+	catalog, err := loadSidecarCatalog()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	appShare := 1.0
+	var containers []ContainerResources
+	for _, spec := range config.Containers {
+		container := ContainerResources{ContainerSpec: spec, CPU: cpu, Memory: fmt.Sprintf("%dMi", int(math.Ceil(memoryMi)))}
+		switch {
+		case spec.Profile != "":
+			profile, err := catalog.profile(spec.Profile)
+			if err != nil {
+				return nil, 0, fmt.Errorf("container %s: %v", spec.Name, err)
+			}
+			if container.Image == "" {
+				container.Image = profile.Image
+			}
+			rps := float64(config.ExpectedLoad) / float64(max(1, replicas))
+			container.CPU = profile.CPU + profile.CPUPer1000RPS*rps/1000
+			container.Memory = fmt.Sprintf("%dMi", profile.MemoryMi)
+		case spec.Share > 0:
+			container.CPU = cpu * spec.Share
+			container.Memory = fmt.Sprintf("%dMi", int(math.Ceil(memoryMi*spec.Share)))
+			if spec.Role == "sidecar" {
+				appShare -= spec.Share
+			}
+		}
+		if container.Image == "" {
+			return nil, 0, fmt.Errorf("container %s: profile %s has no image, set one", spec.Name, spec.Profile)
+		}
+		containers = append(containers, container)
+	}
+	return containers, appShare, nil
+}
+
+// CPU the scheduler reserves for the pod: the app and its sidecars run
+// together, init containers one at a time before them
+func (compute ComputeSpec) podCPU() float64 {
+	running, init := compute.CPU, 0.0
+	for _, container := range compute.Containers {
+		if container.Role == "init" {
+			init = math.Max(init, container.CPU)
+		} else {
+			running += container.CPU
+		}
+	}
+	return math.Max(running, init)
+}
+
+// Memory in Mi the scheduler reserves for the pod, like podCPU
+func (compute ComputeSpec) podMemoryMi() float64 {
+	running, _ := parseMemoryMi(compute.Memory)
+	init := 0.0
+	for _, container := range compute.Containers {
+		memoryMi, _ := parseMemoryMi(container.Memory)
+		if container.Role == "init" {
+			init = math.Max(init, memoryMi)
+		} else {
+			running += memoryMi
+		}
+	}
+	return math.Max(running, init)
+}
+
+// Parses "name=role[:profile or share[:image]]" as the wizard takes extra
+// containers, e.g. "proxy=sidecar:envoy,migrate=init::ghcr.io/acme/migrate:1.0"
+func parseContainers(value string) ([]ContainerSpec, error) {
+	var containers []ContainerSpec
+	for _, entry := range splitList(value) {
+		name, rest, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=role[:profile or share[:image]]", entry)
+		}
+		parts := strings.SplitN(rest, ":", 3)
+		container := ContainerSpec{Name: strings.TrimSpace(name), Role: parts[0]}
+		if len(parts) > 1 && parts[1] != "" {
+			if share, err := strconv.ParseFloat(parts[1], 64); err == nil {
+				container.Share = share
+			} else {
+				container.Profile = parts[1]
+			}
+		}
+		if len(parts) > 2 {
+			container.Image = parts[2]
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// Formats extra containers the way parseContainers reads them. Commands are
+// left out, the manifest itself carries them.
+func formatContainers(containers []ContainerSpec) string {
+	entries := make([]string, len(containers))
+	for i, container := range containers {
+		sizing := container.Profile
+		if container.Share > 0 {
+			sizing = strconv.FormatFloat(container.Share, 'f', -1, 64)
+		}
+		entries[i] = fmt.Sprintf("%s=%s:%s:%s", container.Name, container.Role, sizing, container.Image)
+		entries[i] = strings.TrimRight(entries[i], ":")
+	}
+	return strings.Join(entries, ",")
+}
+
+// Renders the extra containers of one role as entries of a containers or
+// initContainers list. Init containers get the app's configuration, which
+// migrations need.
+func extraContainersString(config *ConfigSpec, compute ComputeSpec, security SecuritySpec, role string) string {
+	var sb strings.Builder
+	for _, container := range compute.Containers {
+		if container.Role != role {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("      - name: %s\n        image: %s\n", container.Name, container.Image))
+		if len(container.Command) > 0 {
+			quoted := make([]string, len(container.Command))
+			for i, arg := range container.Command {
+				quoted[i] = strconv.Quote(arg)
+			}
+			sb.WriteString(fmt.Sprintf("        command: [%s]\n", strings.Join(quoted, ", ")))
+		}
+		sb.WriteString(fmt.Sprintf(`        resources:
+          requests:
+            cpu: "%.2f"
+            memory: "%s"
+          limits:
+            cpu: "%.2f"
+            memory: "%s"
+`, container.CPU*0.8, container.Memory, container.CPU, container.Memory))
+		sb.WriteString(containerSecurityString(security))
+		if role == "init" {
+			sb.WriteString(envFromString(config))
+			if len(config.EnvRefs) > 0 {
+				sb.WriteString("        env:\n" + envRefsString(config.EnvRefs))
+			}
+		}
+		if mount := tmpVolumeMountString(security); mount != "" {
+			sb.WriteString("        volumeMounts:\n" + mount)
+		}
+	}
+	return sb.String()
+}
+
+// Renders the pod's initContainers
+func initContainersString(config *ConfigSpec, compute ComputeSpec, security SecuritySpec) string {
+	containers := extraContainersString(config, compute, security, "init")
+	if containers == "" {
+		return ""
+	}
+	return "      initContainers:\n" + containers
+}
+
+// Describes an extra container for summaries
+func (container ContainerResources) String() string {
+	sizing := "with the whole pod budget"
+	switch {
+	case container.Profile != "":
+		sizing = fmt.Sprintf("from the %s profile", container.Profile)
+	case container.Share > 0:
+		sizing = fmt.Sprintf("with %.0f%% of the pod budget", container.Share*100)
+	}
+	return fmt.Sprintf("CPU=%.2f cores, Memory=%s, %s", container.CPU, container.Memory, sizing)
+}
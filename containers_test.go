@@ -0,0 +1,154 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSizeContainers(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	config := ConfigSpec{
+		ExpectedLoad: 1000,
+		Containers: []ContainerSpec{
+			{Name: "proxy", Role: "sidecar", Profile: "envoy"},
+			{Name: "logs", Role: "sidecar", Share: 0.2, Image: "fluent/fluent-bit:3.1"},
+			{Name: "migrate", Role: "init", Image: "ghcr.io/acme/migrate:1.0"},
+		},
+	}
+	containers, appShare, err := config.sizeContainers(1, 512, 2)
+	if err != nil {
+		t.Fatalf("sizeContainers: %v", err)
+	}
+	if math.Abs(appShare-0.8) > 1e-9 {
+		t.Errorf("app share = %.2f, want 0.80", appShare)
+	}
+
+	want := []struct {
+		cpu    float64
+		memory string
+		image  string
+	}{
+		{0.05 + 0.2*0.5, "64Mi", defaultSidecarCatalog.Sidecars[0].Image}, // Profiled for 500 RPS per replica
+		{0.2, "103Mi", "fluent/fluent-bit:3.1"},
+		{1, "512Mi", "ghcr.io/acme/migrate:1.0"}, // Init containers get the whole budget
+	}
+	if len(containers) != len(want) {
+		t.Fatalf("sized %d containers, want %d", len(containers), len(want))
+	}
+	for i, container := range containers {
+		if math.Abs(container.CPU-want[i].cpu) > 1e-9 || container.Memory != want[i].memory || container.Image != want[i].image {
+			t.Errorf("%s = %.2f cores, %s, %s, want %.2f cores, %s, %s", container.Name,
+				container.CPU, container.Memory, container.Image, want[i].cpu, want[i].memory, want[i].image)
+		}
+	}
+
+	unknown := ConfigSpec{Containers: []ContainerSpec{{Name: "mesh", Role: "sidecar", Profile: "istio"}}}
+	if _, _, err := unknown.sizeContainers(1, 512, 1); err == nil || !strings.Contains(err.Error(), "unknown sidecar profile") {
+		t.Errorf("sizeContainers error = %v, want an unknown profile", err)
+	}
+}
+
+func TestPodResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		compute    ComputeSpec
+		wantCPU    float64
+		wantMemory float64 // in Mi
+	}{
+		{
+			name:       "app alone",
+			compute:    ComputeSpec{CPU: 0.5, Memory: "256Mi"},
+			wantCPU:    0.5,
+			wantMemory: 256,
+		},
+		{
+			name: "sidecars add up, init containers do not",
+			compute: ComputeSpec{CPU: 0.8, Memory: "410Mi", Containers: []ContainerResources{
+				{ContainerSpec: ContainerSpec{Role: "sidecar"}, CPU: 0.2, Memory: "103Mi"},
+				{ContainerSpec: ContainerSpec{Role: "init"}, CPU: 1, Memory: "512Mi"},
+				{ContainerSpec: ContainerSpec{Role: "init"}, CPU: 0.5, Memory: "128Mi"},
+			}},
+			wantCPU:    1,
+			wantMemory: 513,
+		},
+		{
+			name: "largest init container over the running ones",
+			compute: ComputeSpec{CPU: 0.5, Memory: "256Mi", Containers: []ContainerResources{
+				{ContainerSpec: ContainerSpec{Role: "init"}, CPU: 2, Memory: "1Gi"},
+			}},
+			wantCPU:    2,
+			wantMemory: 1024,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if cpu := test.compute.podCPU(); math.Abs(cpu-test.wantCPU) > 1e-9 {
+				t.Errorf("podCPU = %.2f, want %.2f", cpu, test.wantCPU)
+			}
+			if memory := test.compute.podMemoryMi(); memory != test.wantMemory {
+				t.Errorf("podMemoryMi = %.0f, want %.0f", memory, test.wantMemory)
+			}
+		})
+	}
+}
+
+func TestValidateContainers(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []ContainerSpec
+		wantErr    string // Part of the error, none if empty
+	}{
+		{name: "profiled sidecar and init container", containers: []ContainerSpec{
+			{Name: "proxy", Role: "sidecar", Profile: "envoy"},
+			{Name: "migrate", Role: "init", Image: "ghcr.io/acme/migrate:1.0"},
+		}},
+		{name: "clashes with the app container", containers: []ContainerSpec{{Name: "shop-container", Role: "init", Image: "busybox"}}, wantErr: "clashes with the app container"},
+		{name: "name is not a DNS label", containers: []ContainerSpec{{Name: "Proxy", Role: "sidecar", Profile: "envoy"}}, wantErr: "must be a DNS label"},
+		{name: "unknown role", containers: []ContainerSpec{{Name: "proxy", Role: "ambassador", Image: "envoy"}}, wantErr: "role must be one of"},
+		{name: "share and profile", containers: []ContainerSpec{{Name: "proxy", Role: "sidecar", Profile: "envoy", Share: 0.1}}, wantErr: "exclude each other"},
+		{name: "unsized sidecar", containers: []ContainerSpec{{Name: "proxy", Role: "sidecar", Image: "envoy"}}, wantErr: "needs a share of the pod budget or a profile"},
+		{name: "bad image", containers: []ContainerSpec{{Name: "migrate", Role: "init", Image: "Acme/Migrate"}}, wantErr: "not a valid image reference"},
+		{name: "shares leave nothing", containers: []ContainerSpec{
+			{Name: "a", Role: "sidecar", Share: 0.6, Image: "a"},
+			{Name: "b", Role: "sidecar", Share: 0.4, Image: "b"},
+		}, wantErr: "leaving nothing for the app"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateContainers("shop", test.containers)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateContainers: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("validateContainers error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestContainersWizardFormat(t *testing.T) {
+	value := "proxy=sidecar:envoy,logs=sidecar:0.2:fluent/fluent-bit:3.1,migrate=init::ghcr.io/acme/migrate:1.0"
+	containers, err := parseContainers(value)
+	if err != nil {
+		t.Fatalf("parseContainers: %v", err)
+	}
+	want := []ContainerSpec{
+		{Name: "proxy", Role: "sidecar", Profile: "envoy"},
+		{Name: "logs", Role: "sidecar", Share: 0.2, Image: "fluent/fluent-bit:3.1"},
+		{Name: "migrate", Role: "init", Image: "ghcr.io/acme/migrate:1.0"},
+	}
+	if !slices.EqualFunc(containers, want, func(a, b ContainerSpec) bool {
+		return a.Name == b.Name && a.Role == b.Role && a.Profile == b.Profile && a.Share == b.Share && a.Image == b.Image
+	}) {
+		t.Errorf("parseContainers = %+v, want %+v", containers, want)
+	}
+	if got := formatContainers(containers); got != value {
+		t.Errorf("formatContainers = %q, want %q", got, value)
+	}
+}
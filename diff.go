@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Number of unchanged lines shown around each change in a unified diff
+const diffContext = 3
+
+// diffOp is one line of a line-based diff: ' ' kept, '-' removed, '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// fieldChange is a single scalar field that differs between two manifests.
+type fieldChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+// Computes the line operations turning a into b using a longest common
+// subsequence table. Manifests are small, so the quadratic table is fine.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// Renders a unified diff between two texts, or "" when they are identical
+func unifiedDiff(fromName, toName, from, to string) string {
+	ops := diffLines(strings.Split(from, "\n"), strings.Split(to, "\n"))
+
+	// Line numbers in each text before every operation, for hunk headers
+	fromLine := make([]int, len(ops)+1)
+	toLine := make([]int, len(ops)+1)
+	for k, op := range ops {
+		fromLine[k+1], toLine[k+1] = fromLine[k], toLine[k]
+		if op.kind != '+' {
+			fromLine[k+1]++
+		}
+		if op.kind != '-' {
+			toLine[k+1]++
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Grow the hunk while the next change is close enough to share context
+		start := max(0, i-diffContext)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				break
+			}
+			end = run
+		}
+		stop := min(len(ops), end+diffContext)
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n",
+			fromLine[start]+1, fromLine[stop]-fromLine[start],
+			toLine[start]+1, toLine[stop]-toLine[start])
+		for _, op := range ops[start:stop] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.line)
+			b.WriteByte('\n')
+		}
+		i = stop
+	}
+	return b.String()
+}
+
+// Compares the fields of two YAML manifests, reporting each scalar value that
+// was changed, added or removed (e.g. a CPU request going from 0.50 to 1.20)
+func semanticChanges(existing, generated string) ([]fieldChange, error) {
+	oldFields, err := flattenYAML(existing)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing existing manifest: %v", err)
+	}
+	newFields, err := flattenYAML(generated)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated manifest: %v", err)
+	}
+
+	paths := make(map[string]bool)
+	for path := range oldFields {
+		paths[path] = true
+	}
+	for path := range newFields {
+		paths[path] = true
+	}
+
+	var changes []fieldChange
+	for path := range paths {
+		oldValue, inOld := oldFields[path]
+		newValue, inNew := newFields[path]
+		if inOld && inNew && oldValue == newValue {
+			continue
+		}
+		if !inOld {
+			oldValue = "(none)"
+		}
+		if !inNew {
+			newValue = "(none)"
+		}
+		changes = append(changes, fieldChange{Path: path, Old: oldValue, New: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Flattens every document of a YAML stream into dotted paths and scalar
+// values. List entries are keyed by name or port so reordering is not a change.
+func flattenYAML(content string) (map[string]string, error) {
+	fields := make(map[string]string)
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for index := 0; ; index++ {
+		var doc any
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := ""
+		if index > 0 {
+			prefix = fmt.Sprintf("[%d]", index)
+		}
+		flattenValue(prefix, doc, fields)
+	}
+	return fields, nil
+}
+
+func flattenValue(path string, value any, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flattenValue(childPath, child, fields)
+		}
+	case []any:
+		for i, child := range v {
+			key, keyField := listEntryKey(child, i)
+			childPath := fmt.Sprintf("%s[%s]", path, key)
+			flattenValue(childPath, child, fields)
+			if keyField != "" {
+				delete(fields, displayPath(childPath+"."+keyField)) // Already part of the path
+			}
+		}
+	case nil:
+		// Empty values carry no decision
+	default:
+		fields[displayPath(path)] = fmt.Sprint(v)
+	}
+}
+
+// Identifies a list entry by its name or port, falling back to its position.
+// Returns the field the key was taken from, if any.
+func listEntryKey(entry any, index int) (string, string) {
+	if fields, ok := entry.(map[string]any); ok {
+		for _, field := range []string{"name", "containerPort", "port"} {
+			if value, ok := fields[field]; ok {
+				return fmt.Sprint(value), field
+			}
+		}
+	}
+	return fmt.Sprint(index), ""
+}
+
+// Shortens paths into the pod template, where nearly every decision lives
+func displayPath(path string) string {
+	return strings.Replace(path, "spec.template.spec.", "", 1)
+}
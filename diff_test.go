@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []string // Operations as kind followed by line
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, []string{" a", " b"}},
+		{"both empty", nil, nil, nil},
+		{"added to empty", nil, []string{"a"}, []string{"+a"}},
+		{"all removed", []string{"a", "b"}, nil, []string{"-a", "-b"}},
+		{"line added in the middle", []string{"a", "c"}, []string{"a", "b", "c"}, []string{" a", "+b", " c"}},
+		{"line removed at the end", []string{"a", "b"}, []string{"a"}, []string{" a", "-b"}},
+		{"line replaced", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{" a", "-b", "+x", " c"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, op := range diffLines(test.a, test.b) {
+				got = append(got, string(op.kind)+op.line)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("diffLines(%q, %q) = %q, want %q", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSemanticChanges(t *testing.T) {
+	tests := []struct {
+		name                string
+		existing, generated string
+		want                []fieldChange
+	}{
+		{
+			name:      "identical",
+			existing:  "kind: Service\nspec:\n  type: ClusterIP\n",
+			generated: "kind: Service\nspec:\n  type: ClusterIP\n",
+		},
+		{
+			name:      "changed, added and removed fields",
+			existing:  "spec:\n  replicas: 2\n  paused: true\n",
+			generated: "spec:\n  replicas: 3\n  minReadySeconds: 5\n",
+			want: []fieldChange{
+				{Path: "spec.minReadySeconds", Old: "(none)", New: "5"},
+				{Path: "spec.paused", Old: "true", New: "(none)"},
+				{Path: "spec.replicas", Old: "2", New: "3"},
+			},
+		},
+		{
+			name:      "list entries matched by name, not position",
+			existing:  "spec:\n  template:\n    spec:\n      containers:\n        - name: a\n          image: a:1\n        - name: b\n          image: b:1\n",
+			generated: "spec:\n  template:\n    spec:\n      containers:\n        - name: b\n          image: b:1\n        - name: a\n          image: a:2\n",
+			want:      []fieldChange{{Path: "containers[a].image", Old: "a:1", New: "a:2"}},
+		},
+		{
+			name:      "ports keyed by port number",
+			existing:  "spec:\n  ports:\n    - port: 80\n      protocol: TCP\n",
+			generated: "spec:\n  ports:\n    - port: 80\n      protocol: UDP\n",
+			want:      []fieldChange{{Path: "spec.ports[80].protocol", Old: "TCP", New: "UDP"}},
+		},
+		{
+			name:      "later documents are prefixed with their index",
+			existing:  "kind: A\n---\nkind: B\n",
+			generated: "kind: A\n---\nkind: C\n",
+			want:      []fieldChange{{Path: "[1].kind", Old: "B", New: "C"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := semanticChanges(test.existing, test.generated)
+			if err != nil {
+				t.Fatalf("semanticChanges: %v", err)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("semanticChanges = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := semanticChanges("spec: [", "spec: {}"); err == nil {
+		t.Error("semanticChanges accepted an existing manifest that does not parse")
+	}
+}
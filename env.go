@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Env vars the generator sets itself, which the spec cannot declare
+var decisionEnvNames = []string{"NETWORK_BANDWIDTH", "STORAGE_CAPACITY", "STORAGE_CLASS"}
+
+var (
+	envNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	configKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+// EnvRef sets an env var from one key of a ConfigMap or Secret managed
+// outside the generated manifests.
+type EnvRef struct {
+	Name      string `yaml:"name"`
+	ConfigMap string `yaml:"configMap,omitempty"` // Either a ConfigMap
+	Secret    string `yaml:"secret,omitempty"`    // or a Secret
+	Key       string `yaml:"key"`
+}
+
+// EnvSource sets env vars from every key of a ConfigMap or Secret.
+type EnvSource struct {
+	ConfigMap string `yaml:"configMap,omitempty"`
+	Secret    string `yaml:"secret,omitempty"`
+	Prefix    string `yaml:"prefix,omitempty"` // Prepended to every key
+}
+
+// Checks the declared env vars and references
+func validateEnv(env []EnvVar, refs []EnvRef, sources []EnvSource) error {
+	seen := make(map[string]bool)
+	declare := func(name string) error {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("env var name %q must be letters, digits and underscores", name)
+		}
+		if slices.Contains(decisionEnvNames, name) {
+			return fmt.Errorf("env var %s is set by the generator", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("env var %s is declared twice", name)
+		}
+		seen[name] = true
+		return nil
+	}
+
+	for _, v := range env {
+		if err := declare(v.Name); err != nil {
+			return err
+		}
+	}
+	for _, ref := range refs {
+		if err := declare(ref.Name); err != nil {
+			return err
+		}
+		if err := validateEnvObject(ref.ConfigMap, ref.Secret); err != nil {
+			return fmt.Errorf("env ref %s: %v", ref.Name, err)
+		}
+		if !configKeyPattern.MatchString(ref.Key) {
+			return fmt.Errorf("env ref %s: key %q must be letters, digits, '-', '_' or '.'", ref.Name, ref.Key)
+		}
+	}
+	for _, source := range sources {
+		if err := validateEnvObject(source.ConfigMap, source.Secret); err != nil {
+			return fmt.Errorf("envFrom: %v", err)
+		}
+		if source.Prefix != "" && !envNamePattern.MatchString(source.Prefix) {
+			return fmt.Errorf("envFrom: prefix %q must be letters, digits and underscores", source.Prefix)
+		}
+	}
+	return nil
+}
+
+// Checks that exactly one of a ConfigMap or Secret is named, validly
+func validateEnvObject(configMap, secret string) error {
+	if (configMap == "") == (secret == "") {
+		return fmt.Errorf("needs either a configMap or a secret")
+	}
+	if name := configMap + secret; !objectNamePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid object name", name)
+	}
+	return nil
+}
+
+// Name of the ConfigMap holding the spec's plain env values
+func (config *ConfigSpec) configMapName() string {
+	return config.AppName + "-config"
+}
+
+// Parses "NAME=value,..." as the wizard takes plain env vars. Values cannot
+// contain commas here, spec files have no such limit.
+func parseEnv(value string) ([]EnvVar, error) {
+	var env []EnvVar
+	for _, entry := range splitList(value) {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not NAME=value", entry)
+		}
+		env = append(env, EnvVar{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+	}
+	return env, nil
+}
+
+// Parses "NAME=secret:name/key,NAME=configMap:name/key" as the wizard takes
+// env references
+func parseEnvRefs(value string) ([]EnvRef, error) {
+	var refs []EnvRef
+	for _, entry := range splitList(value) {
+		name, source, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not NAME=secret:name/key or NAME=configMap:name/key", entry)
+		}
+		object, key, ok := strings.Cut(source, "/")
+		if !ok {
+			return nil, fmt.Errorf("%q has no /key", entry)
+		}
+		ref := EnvRef{Name: strings.TrimSpace(name), Key: key}
+		var err error
+		ref.ConfigMap, ref.Secret, err = parseEnvObject(object)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// Formats env references the way parseEnvRefs reads them
+func formatEnvRefs(refs []EnvRef) string {
+	entries := make([]string, len(refs))
+	for i, ref := range refs {
+		entries[i] = fmt.Sprintf("%s=%s/%s", ref.Name, formatEnvObject(ref.ConfigMap, ref.Secret), ref.Key)
+	}
+	return strings.Join(entries, ",")
+}
+
+// Parses "secret:name,configMap:name:PREFIX_" as the wizard takes env sources
+func parseEnvSources(value string) ([]EnvSource, error) {
+	var sources []EnvSource
+	for _, entry := range splitList(value) {
+		kind, rest, _ := strings.Cut(entry, ":")
+		name, prefix, _ := strings.Cut(rest, ":")
+		source := EnvSource{Prefix: prefix}
+		var err error
+		source.ConfigMap, source.Secret, err = parseEnvObject(kind + ":" + name)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// Formats env sources the way parseEnvSources reads them
+func formatEnvSources(sources []EnvSource) string {
+	entries := make([]string, len(sources))
+	for i, source := range sources {
+		entries[i] = formatEnvObject(source.ConfigMap, source.Secret)
+		if source.Prefix != "" {
+			entries[i] += ":" + source.Prefix
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// Parses "secret:name" or "configMap:name"
+func parseEnvObject(object string) (configMap, secret string, err error) {
+	kind, name, _ := strings.Cut(object, ":")
+	switch kind {
+	case "configMap":
+		return name, "", nil
+	case "secret":
+		return "", name, nil
+	}
+	return "", "", fmt.Errorf("%q must start with secret: or configMap:", object)
+}
+
+func formatEnvObject(configMap, secret string) string {
+	if secret != "" {
+		return "secret:" + secret
+	}
+	return "configMap:" + configMap
+}
+
+// Renders the container's envFrom: the generated ConfigMap, then the
+// declared sources
+func envFromString(config *ConfigSpec) string {
+	if len(config.Env) == 0 && len(config.EnvFrom) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("        envFrom:\n")
+	if len(config.Env) > 0 {
+		sb.WriteString(fmt.Sprintf("          - configMapRef:\n              name: %s\n", config.configMapName()))
+	}
+	for _, source := range config.EnvFrom {
+		if source.Prefix != "" {
+			sb.WriteString(fmt.Sprintf("          - prefix: %s\n            ", source.Prefix))
+		} else {
+			sb.WriteString("          - ")
+		}
+		if source.Secret != "" {
+			sb.WriteString(fmt.Sprintf("secretRef:\n              name: %s\n", source.Secret))
+		} else {
+			sb.WriteString(fmt.Sprintf("configMapRef:\n              name: %s\n", source.ConfigMap))
+		}
+	}
+	return sb.String()
+}
+
+// Renders the env entries of the references
+func envRefsString(refs []EnvRef) string {
+	var sb strings.Builder
+	for _, ref := range refs {
+		keyRef, object := "configMapKeyRef", ref.ConfigMap
+		if ref.Secret != "" {
+			keyRef, object = "secretKeyRef", ref.Secret
+		}
+		sb.WriteString(fmt.Sprintf(`          - name: %s
+            valueFrom:
+              %s:
+                name: %s
+                key: %s
+`, ref.Name, keyRef, object, ref.Key))
+	}
+	return sb.String()
+}
+
+// Generates the ConfigMap holding the plain env values
+func generateConfigMap(config *ConfigSpec) string {
+	var sb strings.Builder
+	for _, v := range sortedEnv(config.Env) {
+		sb.WriteString(fmt.Sprintf("  %s: %q\n", v.Name, v.Value))
+	}
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+  labels:
+    app: %s
+data:
+%s`, config.configMapName(), config.AppName, sb.String())
+}
+
+// Annotates the pod template with a hash of the ConfigMap's values, so
+// changing them rolls the pods, which envFrom alone would not
+func configChecksumAnnotation(config *ConfigSpec) string {
+	if len(config.Env) == 0 {
+		return ""
+	}
+	hash := sha256.New()
+	for _, v := range sortedEnv(config.Env) {
+		fmt.Fprintf(hash, "%s=%s\n", v.Name, v.Value)
+	}
+	return fmt.Sprintf("        %sconfig-checksum: \"%x\"\n", specAnnotationPrefix, hash.Sum(nil)[:8])
+}
+
+// Env vars ordered by name, as ConfigMap data is
+func sortedEnv(env []EnvVar) []EnvVar {
+	sorted := slices.Clone(env)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
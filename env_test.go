@@ -0,0 +1,97 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     []EnvVar
+		refs    []EnvRef
+		sources []EnvSource
+		wantErr string // Part of the error, none if empty
+	}{
+		{
+			name:    "valid",
+			env:     []EnvVar{{Name: "LOG_LEVEL", Value: "info"}},
+			refs:    []EnvRef{{Name: "DB_PASSWORD", Secret: "db", Key: "password"}},
+			sources: []EnvSource{{ConfigMap: "shared-config", Prefix: "SHARED_"}},
+		},
+		{name: "bad name", env: []EnvVar{{Name: "LOG-LEVEL"}}, wantErr: "letters, digits and underscores"},
+		{name: "decision var", env: []EnvVar{{Name: "STORAGE_CLASS"}}, wantErr: "set by the generator"},
+		{
+			name:    "declared as a value and a ref",
+			env:     []EnvVar{{Name: "TOKEN"}},
+			refs:    []EnvRef{{Name: "TOKEN", Secret: "api", Key: "token"}},
+			wantErr: "declared twice",
+		},
+		{name: "ref to both kinds", refs: []EnvRef{{Name: "TOKEN", ConfigMap: "api", Secret: "api", Key: "token"}}, wantErr: "either a configMap or a secret"},
+		{name: "ref to a bad key", refs: []EnvRef{{Name: "TOKEN", Secret: "api", Key: "a/b"}}, wantErr: `key "a/b"`},
+		{name: "source with a bad name", sources: []EnvSource{{Secret: "Api_Keys"}}, wantErr: "not a valid object name"},
+		{name: "source with a bad prefix", sources: []EnvSource{{Secret: "api", Prefix: "API-"}}, wantErr: `prefix "API-"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateEnv(test.env, test.refs, test.sources)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateEnv: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("validateEnv error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvWizardFormats(t *testing.T) {
+	refs := "DB_PASSWORD=secret:db/password,LOG_LEVEL=configMap:logging/level"
+	parsedRefs, err := parseEnvRefs(refs)
+	if err != nil {
+		t.Fatalf("parseEnvRefs: %v", err)
+	}
+	wantRefs := []EnvRef{{Name: "DB_PASSWORD", Secret: "db", Key: "password"}, {Name: "LOG_LEVEL", ConfigMap: "logging", Key: "level"}}
+	if !slices.Equal(parsedRefs, wantRefs) {
+		t.Errorf("parseEnvRefs = %+v, want %+v", parsedRefs, wantRefs)
+	}
+	if got := formatEnvRefs(parsedRefs); got != refs {
+		t.Errorf("formatEnvRefs = %q, want %q", got, refs)
+	}
+
+	sources := "secret:api-keys,configMap:shared:SHARED_"
+	parsedSources, err := parseEnvSources(sources)
+	if err != nil {
+		t.Fatalf("parseEnvSources: %v", err)
+	}
+	if got := formatEnvSources(parsedSources); got != sources {
+		t.Errorf("formatEnvSources = %q, want %q", got, sources)
+	}
+
+	for _, bad := range []string{"TOKEN", "TOKEN=secret:api", "TOKEN=vault:api/token"} {
+		if _, err := parseEnvRefs(bad); err == nil {
+			t.Errorf("parseEnvRefs(%q) accepted a malformed reference", bad)
+		}
+	}
+}
+
+func TestConfigChecksumAnnotation(t *testing.T) {
+	checksum := func(env ...EnvVar) string {
+		return configChecksumAnnotation(&ConfigSpec{Env: env})
+	}
+	a, b := EnvVar{Name: "A", Value: "1"}, EnvVar{Name: "B", Value: "2"}
+
+	if checksum() != "" {
+		t.Error("configChecksumAnnotation annotated a pod without env values")
+	}
+	if checksum(a, b) != checksum(b, a) {
+		t.Error("checksum depends on the declaration order")
+	}
+	if checksum(a, b) == checksum(a, EnvVar{Name: "B", Value: "3"}) {
+		t.Error("checksum did not change with a value")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Storage forecasting defaults
+const (
+	defaultPlanningHorizonMonths = 12
+	fillThreshold                = 0.8 // Share of the volume at which a resize is due
+	forecastRows                 = 4   // Rows in the growth table, besides today
+)
+
+// forecastPoint is the projected data size at one month of the horizon.
+type forecastPoint struct {
+	Month  int
+	DataMB float64
+}
+
+// StorageForecast is the projected growth of the app's data.
+type StorageForecast struct {
+	HorizonMonths int
+	Points        []forecastPoint
+	FillDate      time.Time // When the data reaches the fill threshold, zero if not within reach
+	FillMonths    int       // Months until the fill threshold from generation, rounded down
+}
+
+// Planning horizon in months, defaulting to a year
+func (config *ConfigSpec) planningHorizon() int {
+	if config.PlanningHorizonMonths > 0 {
+		return config.PlanningHorizonMonths
+	}
+	return defaultPlanningHorizonMonths
+}
+
+// Projected data size in MB after the given number of months.
+//
+// Data compounds by the monthly growth rate. With a retention window, data
+// older than the window is deleted, so the dataset stops growing once it
+// holds a full window of data; this treats today's data as fresh, which
+// errs on the generous side.
+func (config *ConfigSpec) projectedDataMB(months float64) float64 {
+	if config.RetentionMonths > 0 {
+		months = min(months, float64(config.RetentionMonths))
+	}
+	return float64(config.DataSize) * math.Pow(1+config.DataGrowthPercent/100, months)
+}
+
+// Forecasts the data growth over the planning horizon. Returns nil when the
+// data does not grow.
+func (config *ConfigSpec) forecastStorage() *StorageForecast {
+	if config.DataGrowthPercent <= 0 || config.DataSize <= 0 {
+		return nil
+	}
+
+	horizon := config.planningHorizon()
+	forecast := &StorageForecast{HorizonMonths: horizon}
+	step := max(1, horizon/forecastRows)
+	for month := 0; month < horizon; month += step {
+		forecast.Points = append(forecast.Points, forecastPoint{month, config.projectedDataMB(float64(month))})
+	}
+	forecast.Points = append(forecast.Points, forecastPoint{horizon, config.projectedDataMB(float64(horizon))})
+	return forecast
+}
+
+// Capacity in Gi that keeps the data at the horizon under the fill threshold
+func (forecast *StorageForecast) requiredCapacityGi() int {
+	peak := forecast.Points[len(forecast.Points)-1].DataMB
+	return int(math.Ceil(peak / 1024 / fillThreshold))
+}
+
+// Sets the date the data reaches the fill threshold of the given capacity,
+// leaving it zero when retention stops growth short of it
+func (forecast *StorageForecast) setFillDate(config *ConfigSpec, capacityGi float64, now time.Time) {
+	thresholdMB := capacityGi * 1024 * fillThreshold
+	months := math.Log(thresholdMB/float64(config.DataSize)) / math.Log(1+config.DataGrowthPercent/100)
+	if months < 0 {
+		months = 0
+	}
+	if config.RetentionMonths > 0 && months > float64(config.RetentionMonths) {
+		return
+	}
+	whole := int(months)
+	days := int((months - float64(whole)) * 30)
+	forecast.FillDate = now.AddDate(0, whole, days)
+	forecast.FillMonths = whole
+}
+
+// Renders the growth table of the forecast against the decided capacity
+func forecastMarkdown(forecast *StorageForecast, capacity string) string {
+	capacityMi, _ := parseMemoryMi(capacity)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n## Storage Growth Forecast (%d months)\n\n", forecast.HorizonMonths))
+	sb.WriteString(fmt.Sprintf("| Month | Data | Fill of %s |\n", capacity))
+	sb.WriteString("|---|---|---|\n")
+	for _, point := range forecast.Points {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %.0f%% |\n", point.Month, formatDataSize(point.DataMB), point.DataMB/capacityMi*100))
+	}
+	if forecast.FillDate.IsZero() {
+		sb.WriteString(fmt.Sprintf("\nRetention keeps the data below the %.0f%% fill threshold.\n", fillThreshold*100))
+	} else {
+		sb.WriteString(fmt.Sprintf("\nExpected to reach the %.0f%% fill threshold in %s.\n", fillThreshold*100, forecast.FillDate.Format("January 2006")))
+	}
+	return sb.String()
+}
+
+// Formats a size in MB with a readable unit
+func formatDataSize(mb float64) string {
+	if mb >= 1024 {
+		return fmt.Sprintf("%.1f GB", mb/1024)
+	}
+	return fmt.Sprintf("%.0f MB", mb)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestForecastStorage(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       ConfigSpec
+		wantMonths   []int
+		wantPeakMB   float64
+		wantCapacity int // in Gi
+	}{
+		{
+			name:         "a year of compound growth",
+			config:       ConfigSpec{DataSize: 1024, DataGrowthPercent: 10},
+			wantMonths:   []int{0, 3, 6, 9, 12},
+			wantPeakMB:   1024 * math.Pow(1.1, 12),
+			wantCapacity: 4,
+		},
+		{
+			name:         "short horizon steps monthly",
+			config:       ConfigSpec{DataSize: 1024, DataGrowthPercent: 10, PlanningHorizonMonths: 2},
+			wantMonths:   []int{0, 1, 2},
+			wantPeakMB:   1024 * 1.1 * 1.1,
+			wantCapacity: 2,
+		},
+		{
+			name:         "retention stops growth",
+			config:       ConfigSpec{DataSize: 1024, DataGrowthPercent: 10, RetentionMonths: 3},
+			wantMonths:   []int{0, 3, 6, 9, 12},
+			wantPeakMB:   1024 * math.Pow(1.1, 3),
+			wantCapacity: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forecast := test.config.forecastStorage()
+			if forecast == nil {
+				t.Fatal("forecastStorage returned nil for growing data")
+			}
+			var months []int
+			for _, point := range forecast.Points {
+				months = append(months, point.Month)
+			}
+			if !slices.Equal(months, test.wantMonths) {
+				t.Errorf("months = %v, want %v", months, test.wantMonths)
+			}
+			if peak := forecast.Points[len(forecast.Points)-1].DataMB; math.Abs(peak-test.wantPeakMB) > 1e-6 {
+				t.Errorf("peak = %.2fMB, want %.2fMB", peak, test.wantPeakMB)
+			}
+			if capacity := forecast.requiredCapacityGi(); capacity != test.wantCapacity {
+				t.Errorf("requiredCapacityGi = %d, want %d", capacity, test.wantCapacity)
+			}
+		})
+	}
+
+	for _, config := range []ConfigSpec{{DataSize: 1024}, {DataGrowthPercent: 10}} {
+		if forecast := config.forecastStorage(); forecast != nil {
+			t.Errorf("forecastStorage(%+v) = %+v, want nil", config, forecast)
+		}
+	}
+}
+
+func TestSetFillDate(t *testing.T) {
+	now := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		config   ConfigSpec
+		capacity float64 // in Gi
+		want     time.Time
+		months   int
+	}{
+		{"fills within the horizon", ConfigSpec{DataSize: 1024, DataGrowthPercent: 10}, 2, time.Date(2026, time.May, 28, 0, 0, 0, 0, time.UTC), 4},
+		{"fills after the horizon", ConfigSpec{DataSize: 1024, DataGrowthPercent: 10}, 4, time.Date(2027, time.January, 7, 0, 0, 0, 0, time.UTC), 12},
+		{"already past the threshold", ConfigSpec{DataSize: 1024, DataGrowthPercent: 10}, 1, now, 0},
+		{"retention keeps it below", ConfigSpec{DataSize: 1024, DataGrowthPercent: 10, RetentionMonths: 3}, 2, time.Time{}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forecast := &StorageForecast{}
+			forecast.setFillDate(&test.config, test.capacity, now)
+			if !forecast.FillDate.Equal(test.want) {
+				t.Errorf("FillDate = %v, want %v", forecast.FillDate, test.want)
+			}
+			if forecast.FillMonths != test.months {
+				t.Errorf("FillMonths = %d, want %d", forecast.FillMonths, test.months)
+			}
+		})
+	}
+}
+
+func TestPersistentVolumeClaimFillAnnotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		forecast *StorageForecast
+		want     []string
+	}{
+		{
+			name:     "fills within reach",
+			forecast: &StorageForecast{FillDate: time.Date(2027, time.March, 14, 0, 0, 0, 0, time.UTC), FillMonths: 5},
+			want:     []string{`fill-threshold-date: "2027-03"`, `fill-threshold-months: "5"`},
+		},
+		{
+			name:     "retention keeps it below",
+			forecast: &StorageForecast{},
+			want:     []string{`fill-threshold-date: "none"`, `fill-threshold-months: "none"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timedResults := map[string]TimedResult{"storage": {StorageSpec: StorageSpec{
+				Capacity: "10Gi", Class: "standard", AccessMode: "ReadWriteOnce", Forecast: test.forecast,
+			}}}
+			claim := generatePersistentVolumeClaim(&ConfigSpec{AppName: "shop"}, timedResults)
+			for _, annotation := range test.want {
+				if !strings.Contains(claim, specAnnotationPrefix+annotation) {
+					t.Errorf("claim lacks %s%s:\n%s", specAnnotationPrefix, annotation, claim)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// Runs the allocation for a spec file without the wizard, printing the same
+// output the TUI shows. Existing manifests that differ are only replaced when
+// force is set; otherwise their diff is printed and nothing is written. With a
+// merge path the decisions are patched into that file instead. Generated
+// objects that fail schema validation for the Kubernetes version or break a
+// policy rule of error severity fail the run before anything is written,
+// unless acceptViolations is set. Findings are printed before writing.
+func runHeadless(specPath string, force bool, mergePath, kubeVersion string, acceptViolations bool) error {
+	config, err := loadSpec(specPath)
+	if err != nil {
+		return err
+	}
+
+	timedResults, err := collectTimedResourceSpecs(&config)
+	if err != nil {
+		return err
+	}
+
+	m := model{config: config, result: timedResults, kubeVersion: kubeVersion}
+	if err := m.validate(); err != nil {
+		return err
+	}
+	blocking := m.blockingFindings()
+	if blocking != "" || len(m.policyViolations) > 0 {
+		printMarkdown(m.findingsMarkdown())
+		m.findingsShown = true
+	}
+	if blocking != "" && !acceptViolations {
+		return fmt.Errorf("%s, re-run with -accept-violations to write anyway", blocking)
+	}
+	if mergePath != "" {
+		return m.mergeHeadless(mergePath)
+	}
+	if !force {
+		conflicts, err := findManifestConflicts(generateManifests(&config, timedResults))
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			printMarkdown(conflictsMarkdown(conflicts, true))
+			return fmt.Errorf("%d existing manifest(s) differ from the generated ones, re-run with --force to overwrite", len(conflicts))
+		}
+	}
+
+	if err := m.generateAndWriteManifest(writeModeOverwrite); err != nil {
+		return fmt.Errorf("failed to generate/write manifest: %v", err)
+	}
+	printMarkdown(m.generateOutput())
+	return nil
+}
+
+// Patches the decisions into an existing manifest file and reports the
+// fields that changed
+func (m *model) mergeHeadless(path string) error {
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading manifest to merge into: %v", err)
+	}
+
+	report, err := mergeIntoFile(path, generateManifests(&m.config, m.result))
+	if err != nil {
+		return err
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading merged manifest: %v", err)
+	}
+	// A merge that skipped every document leaves the file untouched
+	if string(after) != string(before) {
+		m.k8sManifestPaths = []string{path}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(m.generateOutput())
+	sb.WriteString("\n## Merged Changes\n\n")
+	changes, _ := semanticChanges(string(before), string(after))
+	if len(changes) == 0 {
+		sb.WriteString("- Already up to date\n")
+	}
+	for _, change := range changes {
+		// Added documents are listed whole below
+		var index int
+		if _, err := fmt.Sscanf(change.Path, "[%d]", &index); err == nil && index >= report.Existing {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- `%s`: %s ⇒ %s\n", change.Path, change.Old, change.New))
+	}
+	for _, doc := range report.Added {
+		sb.WriteString(fmt.Sprintf("- Added %s, the merged Deployment relies on it\n", doc))
+	}
+	for _, doc := range report.Skipped {
+		sb.WriteString(fmt.Sprintf("- Skipped %s\n", doc))
+	}
+	printMarkdown(sb.String())
+	return nil
+}
+
+// Prints Markdown rendered with Glamour, plain when stdout is not a terminal
+func printMarkdown(markdown string) {
+	renderer, err := glamour.NewTermRenderer(glamour.WithAutoStyle(), glamour.WithWordWrap(100))
+	if err == nil {
+		if out, err := renderer.Render(markdown); err == nil {
+			markdown = out
+		}
+	}
+	fmt.Print(markdown)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Pull policies a container accepts
+var imagePullPolicies = []string{"Always", "IfNotPresent", "Never"}
+
+// Image emitted until the spec names a repository
+const placeholderImage = "your-app-image:latest"
+
+// Parts of an image reference, following the distribution reference grammar
+var (
+	// Optional registry host with port, then lowercase path components
+	imageRepositoryPattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*(:[0-9]+)?/)?[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*(/[a-z0-9]+((\.|_|__|-+)[a-z0-9]+)*)*$`)
+	imageTagPattern        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	imageDigestPattern     = regexp.MustCompile(`^[a-z0-9]+([+._-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+	// Names of referenced objects such as pull Secrets and ConfigMaps
+	objectNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+)
+
+// ImageSpec represents the decided container image and how it is pulled.
+type ImageSpec struct {
+	Reference   string // e.g. "ghcr.io/acme/shop@sha256:...", the placeholder without a repository
+	Tag         string // Tag left out of a reference pinned by digest, kept as a comment
+	PullPolicy  string // Empty for the placeholder
+	PullSecrets []string
+	Warning     string // Set when a high importance image is not pinned by digest
+}
+
+// Checks the image inputs of a spec
+func validateImage(repository, tag, digest, pullPolicy string, pullSecrets []string) error {
+	if repository == "" && (tag != "" || digest != "") {
+		return fmt.Errorf("imageTag and imageDigest need an imageRepository")
+	}
+	if repository != "" && !imageRepositoryPattern.MatchString(repository) {
+		return fmt.Errorf("imageRepository %q is not a valid repository, e.g. ghcr.io/acme/shop", repository)
+	}
+	if tag != "" && !imageTagPattern.MatchString(tag) {
+		return fmt.Errorf("imageTag %q is not a valid tag", tag)
+	}
+	if digest != "" && !imageDigestPattern.MatchString(digest) {
+		return fmt.Errorf("imageDigest %q is not a valid digest, e.g. sha256:<64 hex digits>", digest)
+	}
+	if pullPolicy != "" && !slices.Contains(imagePullPolicies, pullPolicy) {
+		return fmt.Errorf("imagePullPolicy must be one of %v, got %q", imagePullPolicies, pullPolicy)
+	}
+	for _, secret := range pullSecrets {
+		if !objectNamePattern.MatchString(secret) {
+			return fmt.Errorf("imagePullSecrets: %q is not a valid Secret name", secret)
+		}
+	}
+	return nil
+}
+
+// Splits what the wizard takes as one value, a tag, a digest or both as
+// "tag@digest"
+func parseTagOrDigest(value string) (tag, digest string) {
+	if tag, digest, ok := strings.Cut(value, "@"); ok {
+		return tag, digest
+	}
+	if imageDigestPattern.MatchString(value) {
+		return "", value
+	}
+	return value, ""
+}
+
+// Splits a full image reference such as "ghcr.io/acme/shop:1.2@sha256:..."
+// into repository, tag and digest
+func parseImageReference(reference string) (repository, tag, digest string) {
+	repository, digest, _ = strings.Cut(reference, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:i], repository[i+1:]
+	}
+	return repository, tag, digest
+}
+
+// Decides the image reference and pull policy. A digest pins the image, for
+// high importance apps it replaces the tag so the reference cannot drift.
+func (config *ConfigSpec) decideImage(resultChan chan<- TimedResult) {
+	startTime := time.Now()
+
+	image := ImageSpec{Reference: placeholderImage, PullSecrets: config.ImagePullSecrets}
+	if config.ImageRepository != "" {
+		image.Reference = config.ImageRepository
+		switch {
+		case config.ImageDigest != "" && config.ImportanceLevel == "high":
+			image.Reference += "@" + config.ImageDigest
+			image.Tag = config.ImageTag
+		case config.ImageDigest != "" && config.ImageTag != "":
+			image.Reference += ":" + config.ImageTag + "@" + config.ImageDigest
+		case config.ImageDigest != "":
+			image.Reference += "@" + config.ImageDigest
+		case config.ImageTag != "":
+			image.Reference += ":" + config.ImageTag
+		}
+
+		// Kubernetes' own defaults, made explicit: mutable tags are pulled on
+		// every start
+		image.PullPolicy = config.ImagePullPolicy
+		if image.PullPolicy == "" {
+			image.PullPolicy = "Always"
+			if config.ImageDigest != "" || (config.ImageTag != "" && config.ImageTag != "latest") {
+				image.PullPolicy = "IfNotPresent"
+			}
+		}
+
+		if config.ImageDigest == "" && config.ImportanceLevel == "high" {
+			image.Warning = "High importance images should be pinned by digest, a tag can be moved to a different image"
+		}
+	}
+
+	resultChan <- TimedResult{
+		Name:      "image",
+		ImageSpec: image,
+		Duration:  time.Since(startTime),
+	}
+}
+
+// Renders the container's image and pull policy
+func imageString(image ImageSpec) string {
+	if image.Reference == placeholderImage {
+		return fmt.Sprintf("        image: %s # Replace with your actual image\n", placeholderImage)
+	}
+	comment := ""
+	if image.Tag != "" {
+		comment = fmt.Sprintf(" # %s", image.Tag)
+	}
+	return fmt.Sprintf("        image: %s%s\n        imagePullPolicy: %s\n", image.Reference, comment, image.PullPolicy)
+}
+
+// Renders the pod spec's imagePullSecrets
+func imagePullSecretsString(image ImageSpec) string {
+	if len(image.PullSecrets) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("      imagePullSecrets:\n")
+	for _, secret := range image.PullSecrets {
+		sb.WriteString(fmt.Sprintf("        - name: %s\n", secret))
+	}
+	return sb.String()
+}
+
+// Describes the image for summaries
+func (image ImageSpec) String() string {
+	if image.Reference == placeholderImage {
+		return "Placeholder, set imageRepository"
+	}
+	description := fmt.Sprintf("%s, pull policy %s", image.Reference, image.PullPolicy)
+	if len(image.PullSecrets) > 0 {
+		description += fmt.Sprintf(", pull secrets %s", strings.Join(image.PullSecrets, ", "))
+	}
+	return description
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// importObject holds the parts of a Deployment or PersistentVolumeClaim that
+// describe its current allocation.
+type importObject struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name        string            `yaml:"name"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Replicas *int `yaml:"replicas"`
+		Template struct {
+			Spec struct {
+				Containers       []importContainer `yaml:"containers"`
+				ImagePullSecrets []struct {
+					Name string `yaml:"name"`
+				} `yaml:"imagePullSecrets"`
+				Volumes []struct {
+					PersistentVolumeClaim *struct {
+						ClaimName string `yaml:"claimName"`
+					} `yaml:"persistentVolumeClaim"`
+				} `yaml:"volumes"`
+			} `yaml:"spec"`
+		} `yaml:"template"`
+
+		// PersistentVolumeClaim fields
+		StorageClassName string `yaml:"storageClassName"`
+		Resources        struct {
+			Requests map[string]string `yaml:"requests"`
+		} `yaml:"resources"`
+	} `yaml:"spec"`
+}
+
+type importContainer struct {
+	Name            string `yaml:"name"`
+	Image           string `yaml:"image"`
+	ImagePullPolicy string `yaml:"imagePullPolicy"`
+	Resources       struct {
+		Requests map[string]string `yaml:"requests"`
+		Limits   map[string]string `yaml:"limits"`
+	} `yaml:"resources"`
+	Ports []struct {
+		Name          string `yaml:"name"`
+		ContainerPort int    `yaml:"containerPort"`
+		Protocol      string `yaml:"protocol"`
+	} `yaml:"ports"`
+	Env []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+// importedWorkload is the current allocation of one imported Deployment and
+// the ConfigSpec it is audited against.
+type importedWorkload struct {
+	Name      string
+	Config    ConfigSpec
+	Annotated bool // Config came from tiny-workloads annotations, not estimates
+
+	Replicas        int
+	CPURequest      float64 // in cores, per pod
+	CPULimit        float64 // in cores, per pod
+	MemoryLimit     float64 // in Mi
+	Ports           []int
+	NamedPorts      []ServicePort // Ports with their names and protocols
+	Bandwidth       string
+	StorageCapacity float64 // in Gi
+	StorageClass    string
+}
+
+// auditFinding compares one resource of a workload with the recommendation.
+type auditFinding struct {
+	Resource    string
+	Current     string
+	Recommended string
+	Status      string
+}
+
+// Entry point of the import subcommand: audits existing Deployments against
+// what the deciders would recommend for them
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	tolerance := flags.Float64("tolerance", 0.1, "Relative difference tolerated before a resource counts as over- or under-provisioned")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-workloads import [-tolerance 0.1] manifest.yaml...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("no manifests given")
+	}
+
+	var objects []importObject
+	for _, path := range flags.Args() {
+		fileObjects, err := readImportObjects(path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, fileObjects...)
+	}
+
+	workloads, err := importWorkloads(objects)
+	if err != nil {
+		return err
+	}
+	if len(workloads) == 0 {
+		return fmt.Errorf("no Deployments found in %s", strings.Join(flags.Args(), ", "))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Provisioning Audit\n\n")
+	for _, workload := range workloads {
+		timedResults, err := collectTimedResourceSpecs(&workload.Config)
+		if err != nil {
+			return fmt.Errorf("%s: %v", workload.Name, err)
+		}
+		sb.WriteString(auditMarkdown(workload, auditWorkload(workload, timedResults, *tolerance)))
+	}
+	printMarkdown(sb.String())
+	return nil
+}
+
+// Reads every document of a manifest file
+func readImportObjects(path string) ([]importObject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %v", err)
+	}
+
+	var objects []importObject
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var object importObject
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing manifest %s: %v", path, err)
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// Extracts the allocation of every Deployment, resolving storage through the
+// PersistentVolumeClaims it mounts or the STORAGE_* env vars
+func importWorkloads(objects []importObject) ([]importedWorkload, error) {
+	claims := make(map[string]importObject)
+	for _, object := range objects {
+		if object.Kind == "PersistentVolumeClaim" {
+			claims[object.Metadata.Name] = object
+		}
+	}
+
+	var workloads []importedWorkload
+	for _, object := range objects {
+		if object.Kind != "Deployment" {
+			continue
+		}
+		appName := strings.TrimSuffix(object.Metadata.Name, "-deployment")
+		containers := object.Spec.Template.Spec.Containers
+		if len(containers) == 0 {
+			return nil, fmt.Errorf("Deployment %s has no containers", object.Metadata.Name)
+		}
+		container := containers[0]
+		for _, candidate := range containers {
+			if candidate.Name == appName+"-container" || candidate.Name == appName {
+				container = candidate
+			}
+		}
+
+		// Kubernetes runs one pod when replicas is left out
+		workload := importedWorkload{Name: object.Metadata.Name, Replicas: 1}
+		if object.Spec.Replicas != nil {
+			workload.Replicas = *object.Spec.Replicas
+		}
+		var err error
+		if cpu, ok := container.Resources.Requests["cpu"]; ok {
+			if workload.CPURequest, err = parseCPU(cpu); err != nil {
+				return nil, fmt.Errorf("%s: %v", workload.Name, err)
+			}
+		}
+		if cpu, ok := container.Resources.Limits["cpu"]; ok {
+			if workload.CPULimit, err = parseCPU(cpu); err != nil {
+				return nil, fmt.Errorf("%s: %v", workload.Name, err)
+			}
+		}
+		if memory, ok := container.Resources.Limits["memory"]; ok {
+			if workload.MemoryLimit, err = parseMemoryMi(memory); err != nil {
+				return nil, fmt.Errorf("%s: %v", workload.Name, err)
+			}
+		}
+		for _, port := range container.Ports {
+			workload.Ports = append(workload.Ports, port.ContainerPort)
+			workload.NamedPorts = append(workload.NamedPorts, ServicePort{Name: port.Name, Port: port.ContainerPort, Protocol: port.Protocol})
+		}
+		for _, env := range container.Env {
+			switch env.Name {
+			case "NETWORK_BANDWIDTH":
+				workload.Bandwidth = env.Value
+			case "STORAGE_CAPACITY":
+				if capacity, err := parseMemoryMi(env.Value); err == nil {
+					workload.StorageCapacity = capacity / 1024
+				}
+			case "STORAGE_CLASS":
+				workload.StorageClass = env.Value
+			}
+		}
+
+		// A mounted claim is the real storage allocation
+		for _, volume := range object.Spec.Template.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+			claim, ok := claims[volume.PersistentVolumeClaim.ClaimName]
+			if !ok {
+				continue
+			}
+			if capacity, err := parseMemoryMi(claim.Spec.Resources.Requests["storage"]); err == nil {
+				workload.StorageCapacity = capacity / 1024
+			}
+			workload.StorageClass = claim.Spec.StorageClassName
+		}
+
+		workload.Config = estimateConfig(appName, workload)
+		if container.Image != "" && container.Image != placeholderImage {
+			config := &workload.Config
+			config.ImageRepository, config.ImageTag, config.ImageDigest = parseImageReference(container.Image)
+			config.ImagePullPolicy = container.ImagePullPolicy
+		}
+		for _, secret := range object.Spec.Template.Spec.ImagePullSecrets {
+			workload.Config.ImagePullSecrets = append(workload.Config.ImagePullSecrets, secret.Name)
+		}
+		workload.Annotated, err = applySpecAnnotations(object.Metadata.Annotations, &workload.Config)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", workload.Name, err)
+		}
+		workloads = append(workloads, workload)
+	}
+	return workloads, nil
+}
+
+// Estimates the ConfigSpec behind an allocation by inverting the deciders'
+// rules. Thresholds make this lossy, so annotations take precedence.
+func estimateConfig(appName string, workload importedWorkload) ConfigSpec {
+	config := ConfigSpec{AppName: appName, ImportanceLevel: "medium"}
+	config.applyCalibration() // Best effort, the estimate falls back to the default capacity
+
+	// Only high importance apps get port 443, and their extra CPU is added
+	// to each pod after the load is split across the replicas
+	cpu := workload.CPULimit
+	if slices.Contains(workload.Ports, 443) {
+		config.ImportanceLevel = "high"
+		cpu -= 0.25
+	}
+	cpu *= float64(max(1, workload.Replicas))
+
+	// Ports other than the defaults were declared, named ones can be kept as they are
+	if !slices.Equal(workload.Ports, portNumbers(defaultPorts(config.ImportanceLevel == "high"))) &&
+		!slices.ContainsFunc(workload.NamedPorts, func(port ServicePort) bool { return port.Name == "" }) {
+		config.Ports = workload.NamedPorts
+	}
+
+	load := cpu * config.rpsPerCore()
+	if load > 300 {
+		load = (cpu - 0.75) * config.rpsPerCore()
+	}
+	config.ExpectedLoad = max(0, int(math.Round(load)))
+
+	// Large data sizes show up as premium storage or as extra memory
+	if workload.StorageClass == "premium" && workload.StorageCapacity > 5 {
+		config.DataSize = int(math.Round((workload.StorageCapacity - 5) * 100))
+	} else if workload.MemoryLimit > 256 && workload.MemoryLimit != 512 && workload.MemoryLimit != 1024 {
+		config.DataSize = int(math.Round((workload.MemoryLimit - 256) * 4))
+	}
+
+	// 50Mbps is the bandwidth for up to 25Mbps of traffic
+	if bandwidth, err := parseBandwidthMbps(workload.Bandwidth); err == nil {
+		config.NetworkTraffic = int(bandwidth)
+		if bandwidth <= 50 {
+			config.NetworkTraffic = 25
+		}
+	}
+	return config
+}
+
+// Compares the current allocation with what the deciders recommend
+func auditWorkload(workload importedWorkload, timedResults map[string]TimedResult, tolerance float64) []auditFinding {
+	compute := timedResults["compute"].ComputeSpec
+	network := timedResults["network"].NetworkSpec
+	storage := timedResults["storage"].StorageSpec
+
+	recommendedMemory, _ := parseMemoryMi(compute.Memory)
+	recommendedCapacity, _ := parseMemoryMi(storage.Capacity)
+	recommendedBandwidth, _ := parseBandwidthMbps(network.Bandwidth)
+	currentBandwidth, _ := parseBandwidthMbps(workload.Bandwidth)
+
+	findings := []auditFinding{
+		numericFinding("CPU request", workload.CPURequest, compute.CPU*0.8, tolerance, "%.2f cores"),
+		numericFinding("CPU limit", workload.CPULimit, compute.CPU, tolerance, "%.2f cores"),
+		numericFinding("Memory limit", workload.MemoryLimit, recommendedMemory, tolerance, "%.0fMi"),
+		numericFinding("Storage capacity", workload.StorageCapacity, recommendedCapacity/1024, tolerance, "%.0fGi"),
+		numericFinding("Bandwidth", currentBandwidth, recommendedBandwidth, tolerance, "%.0fMbps"),
+	}
+
+	classStatus := "ok"
+	if workload.StorageClass != storage.Class {
+		classStatus = "differs"
+	}
+	findings = append(findings, auditFinding{"Storage class", orNone(workload.StorageClass), storage.Class, classStatus})
+
+	var missing, extra []string
+	recommendedPorts := portNumbers(network.Ports)
+	for _, port := range recommendedPorts {
+		if !slices.Contains(workload.Ports, port) {
+			missing = append(missing, fmt.Sprint(port))
+		}
+	}
+	for _, port := range workload.Ports {
+		if !slices.Contains(recommendedPorts, port) {
+			extra = append(extra, fmt.Sprint(port))
+		}
+	}
+	portStatus := "ok"
+	switch {
+	case len(missing) > 0:
+		portStatus = "missing " + strings.Join(missing, ", ")
+	case len(extra) > 0:
+		portStatus = "extra " + strings.Join(extra, ", ")
+	}
+	findings = append(findings, auditFinding{"Ports", fmt.Sprint(workload.Ports), fmt.Sprint(recommendedPorts), portStatus})
+
+	return findings
+}
+
+// Classifies a numeric resource relative to its recommendation
+func numericFinding(resource string, current, recommended, tolerance float64, format string) auditFinding {
+	status := "ok"
+	switch {
+	case current == 0:
+		status = "missing"
+	case current > recommended*(1+tolerance):
+		status = "over-provisioned"
+	case current < recommended*(1-tolerance):
+		status = "under-provisioned"
+	}
+
+	currentText := "(none)"
+	if current != 0 {
+		currentText = fmt.Sprintf(format, current)
+	}
+	return auditFinding{resource, currentText, fmt.Sprintf(format, recommended), status}
+}
+
+// Renders one workload's audit as a Markdown section with a findings table
+func auditMarkdown(workload importedWorkload, findings []auditFinding) string {
+	var sb strings.Builder
+
+	source := "estimated from current resources"
+	if workload.Annotated {
+		source = "from tiny-workloads annotations"
+	}
+	sb.WriteString(fmt.Sprintf("## %s\n\n", workload.Name))
+	sb.WriteString(fmt.Sprintf("Spec %s: load=%d RPS, data=%d MB, traffic=%d Mbps, importance=%s\n\n",
+		source,
+		workload.Config.ExpectedLoad,
+		workload.Config.DataSize,
+		workload.Config.NetworkTraffic,
+		workload.Config.ImportanceLevel,
+	))
+
+	sb.WriteString("| Resource | Current | Recommended | Status |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, finding := range findings {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", finding.Resource, finding.Current, finding.Recommended, finding.Status))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// Generates the manifests for a spec, imports them back and audits the
+// result. Anything the importer cannot recover shows up as a finding.
+func TestImportRoundTrip(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name   string
+		config ConfigSpec
+	}{
+		{"medium defaults", ConfigSpec{ExpectedLoad: 100, NetworkTraffic: 25, ImportanceLevel: "medium"}},
+		{"medium busy", ConfigSpec{ExpectedLoad: 450, NetworkTraffic: 200, ImportanceLevel: "medium"}},
+		{"high", ConfigSpec{ExpectedLoad: 150, NetworkTraffic: 100, ImportanceLevel: "high"}},
+		{"high busy across replicas", ConfigSpec{ExpectedLoad: 450, NetworkTraffic: 100, ImportanceLevel: "high"}},
+		{"large data", ConfigSpec{ExpectedLoad: 100, NetworkTraffic: 25, DataSize: 400, ImportanceLevel: "medium"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.AppName = "app"
+			timedResults, err := collectTimedResourceSpecs(&config)
+			if err != nil {
+				t.Fatalf("collectTimedResourceSpecs: %v", err)
+			}
+
+			var contents []string
+			for _, doc := range generateManifests(&config, timedResults) {
+				contents = append(contents, doc.Content)
+			}
+			if err := os.WriteFile("app.yaml", []byte(strings.Join(contents, "\n---\n")), 0644); err != nil {
+				t.Fatal(err)
+			}
+			objects, err := readImportObjects("app.yaml")
+			if err != nil {
+				t.Fatalf("readImportObjects: %v", err)
+			}
+
+			// The spec annotations would hand the config back as it was,
+			// without them the importer has to estimate it
+			for i := range objects {
+				objects[i].Metadata.Annotations = nil
+			}
+			workloads, err := importWorkloads(objects)
+			if err != nil {
+				t.Fatalf("importWorkloads: %v", err)
+			}
+			if len(workloads) != 1 {
+				t.Fatalf("imported %d workloads, want 1", len(workloads))
+			}
+			workload := workloads[0]
+			importedResults, err := collectTimedResourceSpecs(&workload.Config)
+			if err != nil {
+				t.Fatalf("collectTimedResourceSpecs(imported): %v", err)
+			}
+			for _, finding := range auditWorkload(workload, importedResults, 0.1) {
+				if finding.Status != "ok" {
+					t.Errorf("%s: %s, want %s (%s)", finding.Resource, finding.Current, finding.Recommended, finding.Status)
+				}
+			}
+		})
+	}
+}
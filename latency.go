@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Queueing model limits
+const (
+	defaultUtilization = 0.7  // Target utilization when there is no latency SLO
+	maxUtilization     = 0.85 // Beyond this, small load spikes blow up queues
+	minUtilization     = 0.05 // Below this the SLO is treated as unreachable
+	threadedMaxCores   = 4    // Cores one threaded replica is given before scaling out
+)
+
+// Sizes compute from the CPU cost of a request and the p99 latency target.
+//
+// Each core is treated as an M/M/1 queue, whose response time is exponential
+// with mean s/(1-ρ), so its p99 is ln(100)·s/(1-ρ). Keeping that under the
+// target bounds the utilization ρ; the offered load λ·s divided by ρ gives
+// the cores needed. Pooling cores only shortens queues, so this errs on the
+// generous side. Event-loop runtimes cannot use more than one core per
+// process, so they scale out to one core per replica. The cores are spread
+// over at least minReplicas.
+func (config *ConfigSpec) sizeForLatency(minReplicas int) (ComputeSpec, error) {
+	serviceTime := config.CPUTimePerRequestMs / 1000 // in seconds
+	arrivalRate := float64(config.ExpectedLoad)      // requests per second across all replicas
+
+	utilization := defaultUtilization
+	if config.LatencyTargetMs > 0 {
+		target := config.LatencyTargetMs / 1000
+		utilization = min(maxUtilization, 1-math.Log(100)*serviceTime/target)
+		if utilization < minUtilization {
+			reachable := math.Log(100) * serviceTime / (1 - minUtilization) * 1000
+			return ComputeSpec{}, fmt.Errorf("p99 latency target of %.0fms is unreachable with %.1fms of CPU per request, it needs at least %.0fms",
+				config.LatencyTargetMs, config.CPUTimePerRequestMs, math.Ceil(reachable))
+		}
+	}
+
+	totalCores := arrivalRate * serviceTime / utilization
+
+	coresPerReplica := float64(threadedMaxCores)
+	if config.ConcurrencyModel == "event-loop" {
+		coresPerReplica = 1
+	}
+	replicas := max(max(1, minReplicas), int(math.Ceil(totalCores/coresPerReplica)))
+
+	// Little's law: in-flight requests are arrival rate times response time
+	responseTime := serviceTime / (1 - utilization)
+	inFlight := arrivalRate / float64(replicas) * responseTime
+
+	return ComputeSpec{
+		CPU:         totalCores / float64(replicas),
+		Replicas:    replicas,
+		Utilization: utilization,
+		InFlight:    inFlight,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSizeForLatency(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ConfigSpec
+		minReplicas     int
+		wantReplicas    int
+		wantCPU         float64 // Per replica
+		wantUtilization float64
+	}{
+		{
+			name:            "no target runs at the default utilization",
+			config:          ConfigSpec{ExpectedLoad: 100, CPUTimePerRequestMs: 10},
+			minReplicas:     1,
+			wantReplicas:    1,
+			wantCPU:         1 / 0.7,
+			wantUtilization: defaultUtilization,
+		},
+		{
+			name:            "cores spread over the minimum replicas",
+			config:          ConfigSpec{ExpectedLoad: 100, CPUTimePerRequestMs: 10},
+			minReplicas:     3,
+			wantReplicas:    3,
+			wantCPU:         1 / 0.7 / 3,
+			wantUtilization: defaultUtilization,
+		},
+		{
+			name:            "target bounds the utilization",
+			config:          ConfigSpec{ExpectedLoad: 100, CPUTimePerRequestMs: 10, LatencyTargetMs: 100},
+			minReplicas:     1,
+			wantReplicas:    1,
+			wantCPU:         1 / (1 - math.Log(100)*0.1),
+			wantUtilization: 1 - math.Log(100)*0.1,
+		},
+		{
+			name:            "loose target is capped",
+			config:          ConfigSpec{ExpectedLoad: 100, CPUTimePerRequestMs: 10, LatencyTargetMs: 1000},
+			minReplicas:     1,
+			wantReplicas:    1,
+			wantCPU:         1 / maxUtilization,
+			wantUtilization: maxUtilization,
+		},
+		{
+			name:            "threaded replicas scale out past four cores",
+			config:          ConfigSpec{ExpectedLoad: 1000, CPUTimePerRequestMs: 20},
+			minReplicas:     1,
+			wantReplicas:    8,
+			wantCPU:         20 / 0.7 / 8,
+			wantUtilization: defaultUtilization,
+		},
+		{
+			name:            "event loop replicas get one core each",
+			config:          ConfigSpec{ExpectedLoad: 100, CPUTimePerRequestMs: 20, ConcurrencyModel: "event-loop"},
+			minReplicas:     1,
+			wantReplicas:    3,
+			wantCPU:         2 / 0.7 / 3,
+			wantUtilization: defaultUtilization,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			compute, err := test.config.sizeForLatency(test.minReplicas)
+			if err != nil {
+				t.Fatalf("sizeForLatency: %v", err)
+			}
+			if compute.Replicas != test.wantReplicas {
+				t.Errorf("replicas = %d, want %d", compute.Replicas, test.wantReplicas)
+			}
+			if math.Abs(compute.CPU-test.wantCPU) > 1e-9 {
+				t.Errorf("CPU = %.4f, want %.4f", compute.CPU, test.wantCPU)
+			}
+			if math.Abs(compute.Utilization-test.wantUtilization) > 1e-9 {
+				t.Errorf("utilization = %.4f, want %.4f", compute.Utilization, test.wantUtilization)
+			}
+		})
+	}
+
+	unreachable := ConfigSpec{ExpectedLoad: 100, CPUTimePerRequestMs: 10, LatencyTargetMs: 40}
+	if _, err := unreachable.sizeForLatency(1); err == nil {
+		t.Error("sizeForLatency accepted a target shorter than the request's CPU time allows")
+	}
+}
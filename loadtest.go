@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Directory holding tiny-workloads' own state next to the k8s directory
+const stateDir = ".tiny-workloads"
+
+// Throughput per core assumed until a load test calibrates the app
+const defaultRPSPerCore = 150
+
+// calibration is the measured capacity of one app, taken from a load test.
+type calibration struct {
+	RPSPerCore   float64   `json:"rpsPerCore"`
+	AchievedRPS  float64   `json:"achievedRps"`
+	CPU          float64   `json:"cpu"`      // Cores used by the test pod
+	MemoryMi     int       `json:"memoryMi"` // Peak memory of the test pod
+	P95LatencyMs float64   `json:"p95LatencyMs"`
+	P99LatencyMs float64   `json:"p99LatencyMs"`
+	Source       string    `json:"source"`
+	MeasuredAt   time.Time `json:"measuredAt"`
+}
+
+// loadTestResult is what a load test summary tells us, whichever tool made it.
+type loadTestResult struct {
+	RPS          float64
+	P95LatencyMs float64
+	P99LatencyMs float64
+	CPU          float64 // in cores, 0 if not recorded
+	MemoryMi     float64 // 0 if not recorded
+}
+
+// k6Metric is a metric in a k6 summary. handleSummary() nests the numbers in
+// "values" while --summary-export puts them on the metric itself.
+type k6Metric map[string]any
+
+// vegetaReport is the output of `vegeta report -type=json`.
+type vegetaReport struct {
+	Latencies struct {
+		P95 float64 `json:"95th"` // in ns
+		P99 float64 `json:"99th"` // in ns
+	} `json:"latencies"`
+	Rate       float64 `json:"rate"`
+	Throughput float64 `json:"throughput"`
+	Success    float64 `json:"success"`
+}
+
+// Path of the per-app calibration store
+func calibrationPath() string {
+	return filepath.Join(stateDir, "calibration.json")
+}
+
+// Entry point of the calibrate subcommand: measures the app's throughput per
+// core from a load test and saves it for future allocations
+func runCalibrate(args []string) error {
+	flags := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	appName := flags.String("app", "", "Name of the app the load test ran against")
+	k6Path := flags.String("k6", "", "k6 JSON summary (handleSummary or --summary-export)")
+	vegetaPath := flags.String("vegeta", "", "vegeta JSON report (vegeta report -type=json)")
+	cpu := flags.Float64("cpu", 0, "CPU cores used by the test pod, if not recorded in the summary")
+	memory := flags.String("memory", "", "Peak memory of the test pod, e.g. 420Mi, if not recorded in the summary")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-workloads calibrate -app name (-k6 summary.json | -vegeta report.json) [-cpu cores] [-memory quantity]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *appName == "" || (*k6Path == "") == (*vegetaPath == "") {
+		flags.Usage()
+		return fmt.Errorf("-app and exactly one of -k6 or -vegeta are required")
+	}
+
+	var result loadTestResult
+	var err error
+	source := *k6Path
+	if source != "" {
+		result, err = readK6Summary(source)
+	} else {
+		source = *vegetaPath
+		result, err = readVegetaReport(source)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *cpu > 0 {
+		result.CPU = *cpu
+	}
+	if *memory != "" {
+		if result.MemoryMi, err = parseMemoryMi(*memory); err != nil {
+			return err
+		}
+	}
+	if result.CPU <= 0 {
+		return fmt.Errorf("the summary has no pod CPU usage, pass it with -cpu")
+	}
+	if result.RPS <= 0 {
+		return fmt.Errorf("the summary reports no achieved request rate")
+	}
+
+	measured := calibration{
+		RPSPerCore:   result.RPS / result.CPU,
+		AchievedRPS:  result.RPS,
+		CPU:          result.CPU,
+		MemoryMi:     int(math.Ceil(result.MemoryMi)),
+		P95LatencyMs: result.P95LatencyMs,
+		P99LatencyMs: result.P99LatencyMs,
+		Source:       source,
+		MeasuredAt:   time.Now().UTC(),
+	}
+	if err := saveCalibration(*appName, measured); err != nil {
+		return err
+	}
+
+	printMarkdown(calibrationMarkdown(*appName, measured))
+	return nil
+}
+
+// Reads a k6 JSON summary in either of its formats
+func readK6Summary(path string) (loadTestResult, error) {
+	var result loadTestResult
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return result, fmt.Errorf("error reading k6 summary: %v", err)
+	}
+	var summary struct {
+		Metrics map[string]k6Metric `json:"metrics"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return result, fmt.Errorf("error parsing k6 summary %s: %v", path, err)
+	}
+
+	result.RPS = summary.Metrics["http_reqs"].value("rate")
+	result.P95LatencyMs = summary.Metrics["http_req_duration"].value("p(95)")
+	result.P99LatencyMs = summary.Metrics["http_req_duration"].value("p(99)")
+
+	// Pod usage recorded as custom metrics by the test script, if any
+	result.CPU = summary.Metrics["pod_cpu_cores"].value("max", "value")
+	result.MemoryMi = summary.Metrics["pod_memory_bytes"].value("max", "value") / (1 << 20)
+	return result, nil
+}
+
+// Returns the first of the named statistics present on the metric
+func (metric k6Metric) value(names ...string) float64 {
+	values := map[string]any(metric)
+	if nested, ok := metric["values"].(map[string]any); ok {
+		values = nested
+	}
+	for _, name := range names {
+		if number, ok := values[name].(float64); ok {
+			return number
+		}
+	}
+	return 0
+}
+
+// Reads a vegeta JSON report. Throughput only counts successful requests,
+// which is the capacity that matters.
+func readVegetaReport(path string) (loadTestResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return loadTestResult{}, fmt.Errorf("error reading vegeta report: %v", err)
+	}
+	var report vegetaReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return loadTestResult{}, fmt.Errorf("error parsing vegeta report %s: %v", path, err)
+	}
+
+	rps := report.Throughput
+	if rps == 0 {
+		rps = report.Rate * report.Success
+	}
+	return loadTestResult{
+		RPS:          rps,
+		P95LatencyMs: report.Latencies.P95 / float64(time.Millisecond),
+		P99LatencyMs: report.Latencies.P99 / float64(time.Millisecond),
+	}, nil
+}
+
+// Loads the calibrations of all apps; a missing store is empty
+func loadCalibrations() (map[string]calibration, error) {
+	calibrations := make(map[string]calibration)
+
+	data, err := os.ReadFile(calibrationPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return calibrations, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading calibrations: %v", err)
+	}
+	if err := json.Unmarshal(data, &calibrations); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", calibrationPath(), err)
+	}
+	return calibrations, nil
+}
+
+// Stores the calibration of one app, replacing any earlier one
+func saveCalibration(appName string, measured calibration) error {
+	calibrations, err := loadCalibrations()
+	if err != nil {
+		return err
+	}
+	calibrations[appName] = measured
+
+	data, err := json.MarshalIndent(calibrations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding calibrations: %v", err)
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("error creating %s directory: %v", stateDir, err)
+	}
+	if err := os.WriteFile(calibrationPath(), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing calibrations: %v", err)
+	}
+	return nil
+}
+
+// Fills in measured capacity from the calibration store unless the spec
+// already sets it
+func (config *ConfigSpec) applyCalibration() error {
+	if config.RPSPerCore > 0 {
+		return nil
+	}
+	calibrations, err := loadCalibrations()
+	if err != nil {
+		return err
+	}
+	if measured, ok := calibrations[config.AppName]; ok {
+		config.RPSPerCore = measured.RPSPerCore
+		if config.MeasuredMemoryMi == 0 {
+			config.MeasuredMemoryMi = measured.MemoryMi
+		}
+	}
+	return nil
+}
+
+// Throughput per core used for sizing, measured or default
+func (config *ConfigSpec) rpsPerCore() float64 {
+	if config.RPSPerCore > 0 {
+		return config.RPSPerCore
+	}
+	return defaultRPSPerCore
+}
+
+// Renders the measured capacity and how it compares with the default
+func calibrationMarkdown(appName string, measured calibration) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Calibration: %s\n\n", appName))
+	sb.WriteString(fmt.Sprintf("- **Achieved:** %.1f RPS on %.2f cores\n", measured.AchievedRPS, measured.CPU))
+	if measured.P95LatencyMs > 0 || measured.P99LatencyMs > 0 {
+		sb.WriteString(fmt.Sprintf("- **Latency:** p95=%.1fms, p99=%.1fms\n", measured.P95LatencyMs, measured.P99LatencyMs))
+	}
+	if measured.MemoryMi > 0 {
+		sb.WriteString(fmt.Sprintf("- **Peak memory:** %dMi\n", measured.MemoryMi))
+	}
+	sb.WriteString(fmt.Sprintf("- **Throughput per core:** %.1f RPS (default %d, %+.0f%%)\n",
+		measured.RPSPerCore, defaultRPSPerCore, (measured.RPSPerCore/defaultRPSPerCore-1)*100))
+	sb.WriteString(fmt.Sprintf("\nSaved to `%s`, future allocations for %s use the measured capacity.\n", calibrationPath(), appName))
+	return sb.String()
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss" // Use lipgloss for styles
@@ -18,29 +21,109 @@ import (
 
 // ConfigSpec represents the application specifications provided as input.
 type ConfigSpec struct {
-	AppName         string
-	ExpectedLoad    int    // Example: Number of expected requests per second
-	DataSize        int    // Example: Size of data to be processed in MB
-	NetworkTraffic  int    // Example: Expected network bandwidth in Mbps
-	ImportanceLevel string // Example: "high", "medium", "low"
+	AppName         string `yaml:"appName"`
+	ExpectedLoad    int    `yaml:"expectedLoad"`    // Example: Number of expected requests per second
+	DataSize        int    `yaml:"dataSize"`        // Example: Size of data to be processed in MB
+	NetworkTraffic  int    `yaml:"networkTraffic"`  // Example: Expected network bandwidth in Mbps
+	ImportanceLevel string `yaml:"importanceLevel"` // Example: "high", "medium", "low"
+
+	// Container image, a placeholder is emitted until a repository is set
+	ImageRepository  string   `yaml:"imageRepository,omitempty"` // e.g. "ghcr.io/acme/shop"
+	ImageTag         string   `yaml:"imageTag,omitempty"`
+	ImageDigest      string   `yaml:"imageDigest,omitempty"`     // e.g. "sha256:...", pins the image
+	ImagePullPolicy  string   `yaml:"imagePullPolicy,omitempty"` // "Always", "IfNotPresent" or "Never", from the tag if unset
+	ImagePullSecrets []string `yaml:"imagePullSecrets,omitempty"`
+
+	// Request cost and latency SLO, used for queueing-based compute sizing
+	CPUTimePerRequestMs float64 `yaml:"cpuTimePerRequestMs,omitempty"` // Average CPU time one request needs
+	LatencyTargetMs     float64 `yaml:"latencyTargetMs,omitempty"`     // p99 latency objective
+	ConcurrencyModel    string  `yaml:"concurrencyModel,omitempty"`    // "threaded" or "event-loop"
+
+	// Health probes, timed from the startup time and importance level
+	StartupSeconds int    `yaml:"startupSeconds,omitempty"` // Time the app needs to start, assumed from the runtime if unset
+	LivenessPath   string `yaml:"livenessPath,omitempty"`   // Overrides the runtime's conventional path
+	ReadinessPath  string `yaml:"readinessPath,omitempty"`
+
+	// Public exposure, an Ingress or HTTPRoute is generated when a hostname is set
+	Hostname string `yaml:"hostname,omitempty"`
+	Path     string `yaml:"path,omitempty"`    // Path prefix, "/" if unset
+	Gateway  string `yaml:"gateway,omitempty"` // "name" or "namespace/name", routes through the Gateway API when set
+
+	// Ports the app serves, 8080 plus 443 for high importance if none are declared
+	Ports         []ServicePort `yaml:"ports,omitempty"`
+	ExposeMetrics bool          `yaml:"exposeMetrics,omitempty"` // Adds the standard metrics port
+
+	// Who may reach the app, restricted by a NetworkPolicy when set
+	AllowedNamespaces []string          `yaml:"allowedNamespaces,omitempty"`
+	AllowedLabels     map[string]string `yaml:"allowedLabels,omitempty"` // Labels of the pods allowed in
+
+	// Data growth, forecast to size storage for the planning horizon
+	DataGrowthPercent     float64 `yaml:"dataGrowthPercent,omitempty"`     // Monthly growth of the data
+	RetentionMonths       int     `yaml:"retentionMonths,omitempty"`       // Age at which data is deleted, 0 keeps it forever
+	PlanningHorizonMonths int     `yaml:"planningHorizonMonths,omitempty"` // How far ahead to size, 12 if unset
+
+	// Storage performance, selects a class from the storage catalog when set
+	IOPS           int    `yaml:"iops,omitempty"`
+	ThroughputMBps int    `yaml:"throughputMBps,omitempty"`
+	AccessMode     string `yaml:"accessMode,omitempty"` // "RWO", "RWX" or "ROX"
+
+	// Language runtime, sizes heap overhead and GC headroom
+	Runtime string `yaml:"runtime,omitempty"` // "jvm", "go", "node" or "python"
+
+	// App configuration. Plain values are generated into a ConfigMap, which
+	// records them instead of the spec annotations.
+	Env     []EnvVar    `yaml:"env,omitempty"`
+	EnvRefs []EnvRef    `yaml:"envRefs,omitempty"` // Single keys of ConfigMaps or Secrets managed elsewhere
+	EnvFrom []EnvSource `yaml:"envFrom,omitempty"` // Every key of ConfigMaps or Secrets managed elsewhere
+
+	// Sidecars and init containers, sized from the pod's budget or a sidecar profile
+	Containers []ContainerSpec `yaml:"containers,omitempty"`
+
+	// Emits a VerticalPodAutoscaler bounded around the decided resources
+	VerticalAutoscaling bool `yaml:"verticalAutoscaling,omitempty"`
+
+	// Pod Security Standard the pod must pass, hardened to restricted if unset
+	PodSecurityLevel string `yaml:"podSecurityLevel,omitempty"` // "restricted", "baseline" or "privileged"
+
+	// Measured capacity, filled in from load test calibrations when not set
+	RPSPerCore       float64 `yaml:"rpsPerCore,omitempty"`       // Requests per second one core handles
+	MeasuredMemoryMi int     `yaml:"measuredMemoryMi,omitempty"` // Peak memory observed under load
 }
 
 // ComputeSpec represents the decided compute resources.
 type ComputeSpec struct {
-	CPU    float64 // in cores
-	Memory string  // in Mi or Gi
+	CPU      float64 // in cores, per replica
+	Memory   string  // in Mi or Gi
+	Replicas int
+
+	// Set when sized from request cost and latency SLO
+	Utilization float64 // Target CPU utilization per core
+	InFlight    float64 // Requests in flight per replica, by Little's law
+
+	Env []EnvVar // Runtime settings that keep the heap within the memory limit
+
+	Containers []ContainerResources // Sidecars and init containers, CPU and Memory are the app container's
 }
 
 // NetworkSpec represents the decided network resources.
 type NetworkSpec struct {
 	Bandwidth string // in Mbps
-	Ports     []int
+	Ports     []ServicePort
+	Route     *RouteSpec // Public exposure, nil if the app is internal
 }
 
 // StorageSpec represents the decided storage resources.
 type StorageSpec struct {
 	Capacity string // in Gi
 	Class    string // e.g., "standard", "premium"
+
+	AccessMode     string  // e.g., "ReadWriteOnce"
+	IOPS           int     // Required IOPS the class was chosen for
+	ThroughputMBps int     // Required throughput the class was chosen for
+	MonthlyCost    float64 // of the selected class, 0 unless chosen from the catalog
+
+	Forecast *StorageForecast // Projected data growth, nil if the data does not grow
+	Warning  string           // Set when several replicas mount a ReadWriteOnce claim
 }
 
 // TimedResult struct to hold the outcome and duration of each decision function.
@@ -49,8 +132,15 @@ type TimedResult struct {
 	ComputeSpec ComputeSpec
 	NetworkSpec NetworkSpec
 	StorageSpec StorageSpec
-	Error       error
-	Duration    time.Duration
+	ProbeSpec   ProbeSpec
+
+	AvailabilitySpec AvailabilitySpec
+	PrioritySpec     PrioritySpec
+	SecuritySpec     SecuritySpec
+	ImageSpec        ImageSpec
+	AutoscalingSpec  AutoscalingSpec
+	Error            error
+	Duration         time.Duration
 }
 
 // Messages for Bubble Tea
@@ -77,9 +167,29 @@ type model struct {
 	result       map[string]TimedResult
 	err          error
 
+	// Live preview state
+	preview        viewport.Model // Scrollable, highlighted manifest
+	previewDocs    []manifestDocument
+	previewIndex   int    // Which generated document is shown
+	previewSeq     int    // Sequence number of the latest preview request
+	previewInputs  string // Wizard values the latest preview was requested for
+	previewSummary string // Glamour-rendered summary of the previewed decisions
+	previewErr     error
+
+	// Overwrite protection state
+	conflicts []manifestConflict // Existing manifests that differ from the generated ones
+
+	// Schema validation and policy checks of the generated objects
+	kubeVersion      string // Kubernetes version to validate for, resolved from the platform config if empty
+	validation       []objectValidation
+	policyRules      []policyRule
+	policyViolations []policyViolation
+	findingsShown    bool // Validation and policy findings were printed before writing
+
 	// Output state
-	k8sManifestPath string
-	output          string // Glamour-rendered output
+	k8sManifestPaths   []string
+	notMergedManifests []string // Existing files left unchanged by a merge, with the reason
+	output             string   // Glamour-rendered output
 
 	// Terminal size
 	width  int
@@ -92,11 +202,16 @@ const (
 	inputStateText inputState = iota
 	inputStateList
 	inputStateProcessing
+	inputStateConfirmViolations
+	inputStateConfirmOverwrite
 	inputStateDone
 )
 
 // List item for ImportanceLevel
-type item string
+type item struct {
+	name        string
+	description string
+}
 
 func (i item) FilterValue() string { return "" }
 
@@ -111,7 +226,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 		return
 	}
 
-	str := fmt.Sprintf("%d. %s", index+1, string(i))
+	str := fmt.Sprintf("%d. %s", index+1, i.name)
 
 	// Corrected logic to use the style's Render method directly
 	style := itemStyle
@@ -121,6 +236,9 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	}
 
 	fmt.Fprint(w, style.Render(str)) // Call the style's Render method
+	if i.description != "" {
+		fmt.Fprint(w, descriptionStyle.Render(" - "+i.description))
+	}
 }
 
 // Define consistent column widths
@@ -134,6 +252,7 @@ var (
 	itemStyle         = lipgloss.NewStyle().PaddingLeft(2)
 	selectedItemStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00")).PaddingLeft(2) // Green
 	listTitleStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFF00")).Bold(true)     // Yellow
+	descriptionStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666"))
 
 	// Improved styles for layout with consistent column widths
 	labelStyle         = lipgloss.NewStyle().Width(labelWidth).Align(lipgloss.Right).PaddingRight(1)
@@ -152,71 +271,14 @@ var (
 	spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 )
 
-// Initial state of the application
-func initialModel() model {
-	inputs := make([]textinput.Model, 4) // AppName, ExpectedLoad, DataSize, NetworkTraffic
-
-	// AppName
-	inputs[0] = textinput.New()
-	inputs[0].Placeholder = "e.g. my-web-app"
-	inputs[0].Focus()
-	inputs[0].PromptStyle = focusedPromptStyle
-	inputs[0].PlaceholderStyle = blurredPromptStyle
-	inputs[0].CharLimit = 30
-	inputs[0].Prompt = "> "
-	inputs[0].TextStyle = focusedInputStyle    // Apply style to the text itself
-	inputs[0].PromptStyle = focusedPromptStyle // Apply style to the prompt
-
-	// ExpectedLoad
-	inputs[1] = textinput.New()
-	inputs[1].Placeholder = "e.g. 500"
-	inputs[1].CharLimit = 6
-	inputs[1].Prompt = "> "
-	inputs[1].Validate = func(s string) error {
-		_, err := strconv.Atoi(s)
-		if s != "" && err != nil {
-			return fmt.Errorf("must be a number")
-		}
-		return nil
-	}
-	inputs[1].TextStyle = blurredInputStyle
-	inputs[1].PromptStyle = blurredPromptStyle
-
-	// DataSize
-	inputs[2] = textinput.New()
-	inputs[2].Placeholder = "e.g. 100"
-	inputs[2].CharLimit = 6
-	inputs[2].Prompt = "> "
-	inputs[2].Validate = func(s string) error {
-		_, err := strconv.Atoi(s)
-		if s != "" && err != nil {
-			return fmt.Errorf("must be a number")
-		}
-		return nil
-	}
-	inputs[2].TextStyle = blurredInputStyle
-	inputs[2].PromptStyle = blurredPromptStyle
-
-	// NetworkTraffic
-	inputs[3] = textinput.New()
-	inputs[3].Placeholder = "e.g. 75"
-	inputs[3].CharLimit = 6
-	inputs[3].Prompt = "> "
-	inputs[3].Validate = func(s string) error {
-		_, err := strconv.Atoi(s)
-		if s != "" && err != nil {
-			return fmt.Errorf("must be a number")
-		}
-		return nil
-	}
-	inputs[3].TextStyle = blurredInputStyle
-	inputs[3].PromptStyle = blurredPromptStyle
+// Initial state of the application, offering the given importance tiers
+func initialModel(tiers []importanceTier) model {
+	inputs := newWizardInputs()
 
 	// ImportanceLevel list
-	items := []list.Item{
-		item("high"),
-		item("medium"),
-		item("low"),
+	items := make([]list.Item, len(tiers))
+	for i, tier := range tiers {
+		items[i] = item{name: tier.Name, description: tier.Description}
 	}
 	listModel := list.New(items, itemDelegate{}, 20, 10)
 	listModel.Title = "Select Importance Level"
@@ -224,18 +286,23 @@ func initialModel() model {
 	listModel.SetFilteringEnabled(false)
 	listModel.Styles.Title = listTitleStyle
 
-	return model{
+	m := model{
 		inputs:     inputs,
 		list:       listModel,
 		focused:    0,
 		inputState: inputStateText,
 		spinner:    spinnerFrames[0],
+		preview:    viewport.New(previewDefaultWidth, previewStackedHeight),
 	}
+	m.previewInputs = m.previewInputKey()
+	m.resizePreview()
+	return m
 }
 
 // Bubble Tea Init function
 func (m model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, tickCmd()) // Start blinking and spinner ticking
+	// Start blinking, spinner ticking and the first preview
+	return tea.Batch(textinput.Blink, tickCmd(), previewCmd(m.previewSeq, m.previewConfig()))
 }
 
 // Command to send a TickMsg periodically for the spinner
@@ -251,6 +318,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.inputState == inputStateConfirmViolations {
+			return m.updateConfirmViolations(msg)
+		}
+		if m.inputState == inputStateConfirmOverwrite {
+			return m.updateConfirmOverwrite(msg)
+		}
+
 		switch msg.Type {
 		case tea.KeyCtrlC:
 			m.quitting = true
@@ -282,33 +356,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.inputState = inputStateDone
 					return m, nil
 				}
-				m.config.ImportanceLevel = string(selectedItem)
+				m.config.ImportanceLevel = selectedItem.name
 
 				// Collect all inputs
-				m.config.AppName = m.inputs[0].Value()
-				load, err := strconv.Atoi(m.inputs[1].Value())
-				if err != nil {
-					m.err = fmt.Errorf("invalid expected load: %v", err)
-					m.inputState = inputStateDone
-					return m, nil
-				}
-				m.config.ExpectedLoad = load
-
-				dataSize, err := strconv.Atoi(m.inputs[2].Value())
-				if err != nil {
-					m.err = fmt.Errorf("invalid data size: %v", err)
+				if err := m.applyInputs(&m.config); err != nil {
+					m.err = err
 					m.inputState = inputStateDone
 					return m, nil
 				}
-				m.config.DataSize = dataSize
-
-				networkTraffic, err := strconv.Atoi(m.inputs[3].Value())
-				if err != nil {
-					m.err = fmt.Errorf("invalid network traffic: %v", err)
+				if err := m.config.validate(); err != nil {
+					m.err = err
 					m.inputState = inputStateDone
 					return m, nil
 				}
-				m.config.NetworkTraffic = networkTraffic
 
 				// Start the background processing
 				m.processing = true
@@ -316,6 +376,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, tea.Batch(processResourceAllocation(m.config), tickCmd()) // Start processing and continue spinner
 			}
 
+		case tea.KeyPgUp:
+			m.preview.HalfPageUp()
+			return m, nil
+
+		case tea.KeyPgDown:
+			m.preview.HalfPageDown()
+			return m, nil
+
+		case tea.KeyCtrlO:
+			// Cycle through the generated documents in the preview
+			m.nextPreviewDocument()
+			return m, nil
+
 		case tea.KeyShiftTab, tea.KeyCtrlP:
 			// Move to the previous input
 			if m.inputState == inputStateText {
@@ -359,18 +432,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ProcessCompleteMsg:
 		m.processing = false
 		m.result = msg
-		m.inputState = inputStateDone
 
-		// Generate and write Kubernetes manifest
-		err := m.generateAndWriteManifest()
-		if err != nil {
-			m.err = fmt.Errorf("failed to generate/write manifest: %v", err)
+		// Validate before anything is written, invalid objects and policy
+		// errors are only written once the user confirms
+		if err := m.validate(); err != nil {
+			m.err = err
+			m.inputState = inputStateDone
+			return m, tea.Quit
+		}
+		if m.blockingFindings() != "" {
+			m.inputState = inputStateConfirmViolations
+			return m, nil
 		}
 
-		// Generate output string with Glamour
-		m.output = m.generateOutput()
-
-		return m, tea.Quit // Quit after processing is done and output is generated
+		cmd = m.checkConflicts()
+		return m, cmd
 
 	case ProcessErrorMsg:
 		m.processing = false
@@ -389,9 +465,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tickCmd() // Continue ticking
 		}
 
+	case previewDebounceMsg:
+		return m, m.debouncedPreview(msg)
+
+	case PreviewMsg:
+		m.applyPreview(msg)
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.resizePreview()
 		// Handle window resizing for the list
 		if m.inputState == inputStateList {
 			m.list.SetWidth(msg.Width)
@@ -404,9 +488,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
 	} else if m.inputState == inputStateList {
 		m.list, cmd = m.list.Update(msg)
+	} else {
+		return m, cmd
 	}
 
-	return m, cmd
+	// Keep the preview in sync with what has been entered
+	return m, tea.Batch(cmd, m.refreshPreview())
 }
 
 // Bubble Tea View function
@@ -419,6 +506,14 @@ func (m model) View() string {
 		return fmt.Sprintf("%s Processing resource allocation for %s...\n", m.spinner, m.config.AppName)
 	}
 
+	if m.inputState == inputStateConfirmViolations {
+		return m.confirmViolationsView()
+	}
+
+	if m.inputState == inputStateConfirmOverwrite {
+		return m.confirmOverwriteView()
+	}
+
 	if m.inputState == inputStateDone {
 		if m.err != nil {
 			// Render the error using Glamour
@@ -436,21 +531,28 @@ func (m model) View() string {
 		return out
 	}
 
-	// Render the input wizard
+	// Render the input wizard next to the live preview
 	var b strings.Builder
 
 	b.WriteString("Enter application specifications:\n\n")
 
-	// Use a consistent grid layout for labels and inputs
-	inputLabels := []string{"App Name:", "Expected Load (RPS):", "Data Size (MB):", "Network Traffic (Mbps):"}
-	for i := range m.inputs {
+	// Use a consistent grid layout for labels and inputs, scrolled so the
+	// focused input stays visible on short terminals
+	first, last := m.visibleInputs()
+	if first > 0 {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("↑ %d more", first)) + "\n")
+	}
+	for i := first; i < last; i++ {
 		// Create a consistent row with aligned labels and inputs
 		row := lipgloss.JoinHorizontal(lipgloss.Top,
-			labelStyle.Render(inputLabels[i]),
+			labelStyle.Render(wizardFields[i].label),
 			textInputViewStyle.Render(m.inputs[i].View()),
 		)
 		b.WriteString(inputRowStyle.Render(row) + "\n")
 	}
+	if last < len(m.inputs) {
+		b.WriteString(labelStyle.Render(fmt.Sprintf("↓ %d more", len(m.inputs)-last)) + "\n")
+	}
 
 	if m.inputState == inputStateList {
 		b.WriteString("\n")
@@ -459,10 +561,18 @@ func (m model) View() string {
 		listPlaceholder := labelStyle.Render("Select Importance Level:")
 		b.WriteString(inputRowStyle.Render(listPlaceholder))
 	}
+	b.WriteString("\n")
+	b.WriteString(m.previewSummary)
 
-	b.WriteString("\nPress Enter to continue, Tab/Shift+Tab to navigate, Ctrl+C to quit.\n")
+	form := lipgloss.NewStyle().Width(formColumnWidth).Render(b.String())
+	var layout string
+	if m.width >= previewMinSplitWidth {
+		layout = lipgloss.JoinHorizontal(lipgloss.Top, form, m.previewView())
+	} else {
+		layout = lipgloss.JoinVertical(lipgloss.Left, form, m.previewView())
+	}
 
-	return b.String()
+	return layout + "\nPress Enter to continue, Tab/Shift+Tab to navigate, PgUp/PgDn to scroll, Ctrl+O to switch document, Ctrl+C to quit.\n"
 }
 
 // Helper function to run resource allocation in a goroutine
@@ -478,13 +588,29 @@ func processResourceAllocation(config ConfigSpec) tea.Cmd {
 
 // The resource allocation logic
 func collectTimedResourceSpecs(config *ConfigSpec) (map[string]TimedResult, error) {
-	resultChan := make(chan TimedResult, 3)
-	go config.decideCompute(resultChan)
-	go config.decideNetwork(resultChan)
-	go config.decideStorage(resultChan)
+	// Prefer capacity measured by load tests over the defaults
+	if err := config.applyCalibration(); err != nil {
+		return nil, err
+	}
+
+	deciders := []func(chan<- TimedResult){
+		config.decideCompute,
+		config.decideNetwork,
+		config.decideStorage,
+		config.decideProbes,
+		config.decideAvailability,
+		config.decidePriority,
+		config.decideSecurity,
+		config.decideImage,
+		config.decideAutoscaling,
+	}
+	resultChan := make(chan TimedResult, len(deciders))
+	for _, decide := range deciders {
+		go decide(resultChan)
+	}
 
 	timedResults := make(map[string]TimedResult)
-	for i := 0; i < 3; i++ {
+	for range deciders {
 		result := <-resultChan
 		if result.Error != nil {
 			return nil, fmt.Errorf("error in %s decision: %v", result.Name, result.Error)
@@ -495,29 +621,102 @@ func collectTimedResourceSpecs(config *ConfigSpec) (map[string]TimedResult, erro
 }
 
 func (config *ConfigSpec) decideCompute(resultChan chan<- TimedResult) {
-	fmt.Printf("Starting compute decision for %s...\n", config.AppName)
 	startTime := time.Now()
 	time.Sleep(time.Millisecond * 200) // Simulate some computation
 
-	cpu := float64(config.ExpectedLoad) / 150 // Adjusted: Even tinier compute
-	memory := "256Mi"                         // Default tinier memory
+	cpu := float64(config.ExpectedLoad) / config.rpsPerCore() // Adjusted: Even tinier compute
+	memory := "256Mi"                                         // Default tinier memory
+
+	tier, err := config.tier()
+	if err != nil {
+		resultChan <- TimedResult{Name: "compute", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+
+	// Availability guarantees of the importance level need a minimum of
+	// replicas, which share the load
+	policy, err := config.availabilityPolicy()
+	if err != nil {
+		resultChan <- TimedResult{Name: "compute", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+	compute := ComputeSpec{Replicas: max(1, policy.MinReplicas)}
+
+	// Known request cost replaces the throughput guess with queueing math
+	sizedForLatency := config.CPUTimePerRequestMs > 0
+	if sizedForLatency {
+		sized, err := config.sizeForLatency(compute.Replicas)
+		if err != nil {
+			resultChan <- TimedResult{Name: "compute", Error: err, Duration: time.Since(startTime)}
+			return
+		}
+		compute = sized
+		cpu = sized.CPU
+	}
 
 	if config.ExpectedLoad > 300 {
-		cpu += 0.75
+		if !sizedForLatency {
+			cpu += 0.75
+		}
 		memory = "512Mi"
 	}
-	if config.ImportanceLevel == "high" {
-		cpu += 0.25
-		memory = "1Gi"
+	if !sizedForLatency {
+		// The tier scales what one replica gets of the total
+		cpu = tier.scaleCPU(cpu / float64(compute.Replicas))
+	}
+	if tier.Memory != "" {
+		memory = tier.Memory
 	}
 	if config.DataSize > 50 {
 		memory = fmt.Sprintf("%dMi", 256+(config.DataSize/4))
 	}
+	if tier.MemoryMultiplier > 0 {
+		appMi, _ := parseMemoryMi(memory)
+		memory = fmt.Sprintf("%dMi", int(math.Ceil(tier.scaleMemory(appMi))))
+	}
+
+	// The rules above size what the app needs, the runtime adds its own
+	if config.Runtime != "" {
+		appMi, _ := parseMemoryMi(memory)
+		memory = fmt.Sprintf("%dMi", config.runtimeMemoryLimit(appMi))
+	}
+
+	// Never go below the memory a load test measured, plus 20% headroom
+	if config.MeasuredMemoryMi > 0 {
+		decided, _ := parseMemoryMi(memory)
+		if measured := float64(config.MeasuredMemoryMi) * 1.2; measured > decided {
+			memory = fmt.Sprintf("%dMi", int(math.Ceil(measured)))
+		}
+	}
+	limitMi, _ := parseMemoryMi(memory)
+
+	// What was decided so far is the pod's budget, sidecars with a share
+	// split it with the app
+	containers, appShare, err := config.sizeContainers(cpu, limitMi, compute.Replicas)
+	if err != nil {
+		resultChan <- TimedResult{Name: "compute", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+	compute.Containers = containers
+	if appShare < 1 {
+		cpu *= appShare
+		limitMi = math.Ceil(limitMi * appShare)
+		memory = fmt.Sprintf("%dMi", int(limitMi))
+	}
+
+	// Values the spec sets itself win over the runtime's
+	for _, v := range config.runtimeEnv(int(limitMi)) {
+		if !slices.ContainsFunc(config.Env, func(declared EnvVar) bool { return declared.Name == v.Name }) {
+			compute.Env = append(compute.Env, v)
+		}
+	}
 
 	duration := time.Since(startTime)
+	compute.CPU = cpu
+	compute.Memory = memory
 	resultChan <- TimedResult{
 		Name:        "compute",
-		ComputeSpec: ComputeSpec{CPU: cpu, Memory: memory},
+		ComputeSpec: compute,
 		Duration:    duration,
 		Error:       nil,
 	}
@@ -525,24 +724,24 @@ func (config *ConfigSpec) decideCompute(resultChan chan<- TimedResult) {
 }
 
 func (config *ConfigSpec) decideNetwork(resultChan chan<- TimedResult) {
-	fmt.Printf("Starting network decision for %s...\n", config.AppName)
 	startTime := time.Now()
 	time.Sleep(time.Millisecond * 150) // Simulate some computation
 
 	bandwidth := "50Mbps"
-	ports := []int{8080}
+	ports, err := config.decidePorts()
+	if err != nil {
+		resultChan <- TimedResult{Name: "network", Error: err, Duration: time.Since(startTime)}
+		return
+	}
 
 	if config.NetworkTraffic > 25 {
 		bandwidth = "200Mbps"
 	}
-	if config.ImportanceLevel == "high" {
-		ports = append(ports, 443)
-	}
 
 	duration := time.Since(startTime)
 	resultChan <- TimedResult{
 		Name:        "network",
-		NetworkSpec: NetworkSpec{Bandwidth: bandwidth, Ports: ports},
+		NetworkSpec: NetworkSpec{Bandwidth: bandwidth, Ports: ports, Route: config.decideRoute(ports)},
 		Duration:    duration,
 		Error:       nil,
 	}
@@ -550,52 +749,200 @@ func (config *ConfigSpec) decideNetwork(resultChan chan<- TimedResult) {
 }
 
 func (config *ConfigSpec) decideStorage(resultChan chan<- TimedResult) {
-	fmt.Printf("Starting storage decision for %s...\n", config.AppName)
 	startTime := time.Now()
 	time.Sleep(time.Millisecond * 100) // Simulate some computation
 
 	capacity := "5Gi"
 	class := "standard"
 
+	tier, err := config.tier()
+	if err != nil {
+		resultChan <- TimedResult{Name: "storage", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+
 	if config.DataSize > 250 {
 		capacity = fmt.Sprintf("%dGi", 5+(config.DataSize/100))
 		class = "premium"
 	}
-	if config.ImportanceLevel == "high" {
-		capacity = "20Gi"
+	if tier.StorageCapacityGi > 0 {
+		capacity = fmt.Sprintf("%dGi", tier.StorageCapacityGi)
+	}
+	if tier.StorageClass != "" {
+		class = tier.StorageClass
+	}
+
+	// Growing data needs room for the horizon, not just for today
+	forecast := config.forecastStorage()
+	if forecast != nil {
+		capacityMi, _ := parseMemoryMi(capacity)
+		if required := forecast.requiredCapacityGi(); float64(required*1024) > capacityMi {
+			capacity = fmt.Sprintf("%dGi", required)
+			capacityMi = float64(required * 1024)
+		}
+		forecast.setFillDate(config, capacityMi/1024, time.Now())
+	}
+
+	// Replicas on different nodes cannot attach the same ReadWriteOnce
+	// claim. Sharing one is the spec's call, since it costs a network file
+	// system class, so the claim stays as decided and the summary warns.
+	replicas, err := config.replicaCount()
+	if err != nil {
+		resultChan <- TimedResult{Name: "storage", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+	var warning string
+	if replicas > 1 && config.accessMode() == "RWO" {
+		warning = fmt.Sprintf("%d replicas mount a ReadWriteOnce claim, which only pods on one node can attach; set accessMode to RWX or ROX if they share the data", replicas)
+	}
+
+	// Performance requirements pick the cheapest class of the catalog that meets them
+	var monthlyCost float64
+	if config.hasStorageRequirements() {
+		catalog, err := loadStorageCatalog()
+		if err == nil {
+			capacityMi, _ := parseMemoryMi(capacity)
+			var offer storageClassOffer
+			offer, monthlyCost, err = config.selectStorageClass(catalog, capacityMi/1024)
+			class = offer.Name
+		}
+		if err != nil {
+			resultChan <- TimedResult{Name: "storage", Error: err, Duration: time.Since(startTime)}
+			return
+		}
 	}
 
 	duration := time.Since(startTime)
 	resultChan <- TimedResult{
-		Name:        "storage",
-		StorageSpec: StorageSpec{Capacity: capacity, Class: class},
-		Duration:    duration,
-		Error:       nil,
+		Name: "storage",
+		StorageSpec: StorageSpec{
+			Capacity:       capacity,
+			Class:          class,
+			AccessMode:     accessModes[config.accessMode()],
+			IOPS:           config.IOPS,
+			ThroughputMBps: config.ThroughputMBps,
+			MonthlyCost:    monthlyCost,
+			Forecast:       forecast,
+			Warning:        warning,
+		},
+		Duration: duration,
+		Error:    nil,
 	}
 	// fmt.Printf("Storage decision finished for %s.\n", config.AppName)
 }
 
+// manifestDocument is a single generated Kubernetes object and the file it is
+// written to inside the k8s directory.
+type manifestDocument struct {
+	Kind    string
+	Name    string
+	File    string
+	Content string
+}
+
+// Generates every Kubernetes document for the app, in the order they are
+// written and shown in the preview pane
+func generateManifests(config *ConfigSpec, timedResults map[string]TimedResult) []manifestDocument {
+	docs := []manifestDocument{
+		{
+			Kind:    "Deployment",
+			Name:    fmt.Sprintf("%s-deployment", config.AppName),
+			File:    fmt.Sprintf("%s-deployment.yaml", config.AppName),
+			Content: generateKubernetesManifest(config, timedResults),
+		},
+	}
+	if len(config.Env) > 0 {
+		docs = append(docs, manifestDocument{
+			Kind:    "ConfigMap",
+			Name:    config.configMapName(),
+			File:    fmt.Sprintf("%s-configmap.yaml", config.AppName),
+			Content: generateConfigMap(config),
+		})
+	}
+	docs = append(docs, manifestDocument{
+		Kind:    "PersistentVolumeClaim",
+		Name:    fmt.Sprintf("%s-data", config.AppName),
+		File:    fmt.Sprintf("%s-pvc.yaml", config.AppName),
+		Content: generatePersistentVolumeClaim(config, timedResults),
+	})
+	docs = append(docs, manifestDocument{
+		Kind:    "Service",
+		Name:    fmt.Sprintf("%s-service", config.AppName),
+		File:    fmt.Sprintf("%s-service.yaml", config.AppName),
+		Content: generateService(config, timedResults),
+	})
+	if route := timedResults["network"].NetworkSpec.Route; route != nil {
+		docs = append(docs, manifestDocument{
+			Kind:    route.Kind,
+			Name:    fmt.Sprintf("%s-route", config.AppName),
+			File:    fmt.Sprintf("%s-%s.yaml", config.AppName, strings.ToLower(route.Kind)),
+			Content: generateRoute(config, timedResults),
+		})
+	}
+	if timedResults["availability"].AvailabilitySpec.Policy.MaxUnavailable != "" {
+		docs = append(docs, manifestDocument{
+			Kind:    "PodDisruptionBudget",
+			Name:    fmt.Sprintf("%s-pdb", config.AppName),
+			File:    fmt.Sprintf("%s-pdb.yaml", config.AppName),
+			Content: generatePodDisruptionBudget(config, timedResults),
+		})
+	}
+	if class := timedResults["priority"].PrioritySpec.Class; class.Name != "" && !class.Existing {
+		docs = append(docs, manifestDocument{
+			Kind:    "PriorityClass",
+			Name:    class.Name,
+			File:    fmt.Sprintf("priorityclass-%s.yaml", class.Name),
+			Content: generatePriorityClass(config, timedResults),
+		})
+	}
+	if config.restrictsIngress() {
+		docs = append(docs, manifestDocument{
+			Kind:    "NetworkPolicy",
+			Name:    fmt.Sprintf("%s-ingress", config.AppName),
+			File:    fmt.Sprintf("%s-networkpolicy.yaml", config.AppName),
+			Content: generateNetworkPolicy(config, timedResults),
+		})
+	}
+	if config.VerticalAutoscaling {
+		docs = append(docs, manifestDocument{
+			Kind:    "VerticalPodAutoscaler",
+			Name:    fmt.Sprintf("%s-vpa", config.AppName),
+			File:    fmt.Sprintf("%s-vpa.yaml", config.AppName),
+			Content: generateVerticalPodAutoscaler(config, timedResults),
+		})
+	}
+	return docs
+}
+
 // Generates the Kubernetes manifest string
-func generateKubernetesManifest(appName string, timedResults map[string]TimedResult, ports []int) string {
+func generateKubernetesManifest(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	appName := config.AppName
+	ports := timedResults["network"].NetworkSpec.Ports
+
 	var portString string
 	for _, port := range ports {
 		portString += fmt.Sprintf(`
-          - containerPort: %d
-            protocol: TCP
-        `, port)
+          - name: %s
+            containerPort: %d
+            protocol: %s
+        `, port.Name, port.Port, port.Protocol)
 	}
 
 	computeResult := timedResults["compute"]
 	networkResult := timedResults["network"]
 	storageResult := timedResults["storage"]
+	security := timedResults["security"].SecuritySpec
+	image := timedResults["image"].ImageSpec
 
 	manifest := fmt.Sprintf(`
 apiVersion: apps/v1
 kind: Deployment
 metadata:
   name: %s-deployment
+  annotations:
+%s
 spec:
-  replicas: 1
+  replicas: %d
   selector:
     matchLabels:
       app: %s
@@ -603,11 +950,11 @@ spec:
     metadata:
       labels:
         app: %s
-    spec:
-      containers:
+      annotations:
+%s%s    spec:
+%s%s%s%s%s      containers:
       - name: %s-container
-        image: your-app-image:latest # Replace with your actual image
-        resources:
+%s        resources:
           requests:
             cpu: "%.2f"
             memory: "%s"
@@ -616,47 +963,166 @@ spec:
             memory: "%s"
         ports:
 %s
-        env:
+%s%s%s        env:
           - name: NETWORK_BANDWIDTH
             value: "%s"
           - name: STORAGE_CAPACITY
             value: "%s"
           - name: STORAGE_CLASS
             value: "%s"
-`, appName, appName, appName, appName,
+%s%s        volumeMounts:
+          - name: data
+            mountPath: /data
+%s%s      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: %s-data
+%s`, appName, specAnnotations(config), max(1, computeResult.ComputeSpec.Replicas), appName, appName,
+		bandwidthAnnotations(networkResult.NetworkSpec),
+		configChecksumAnnotation(config),
+		priorityClassString(timedResults["priority"].PrioritySpec),
+		schedulingString(appName, timedResults["availability"].AvailabilitySpec.Policy),
+		podSecurityString(security),
+		imagePullSecretsString(image),
+		initContainersString(config, computeResult.ComputeSpec, security),
+		appName,
+		imageString(image),
 		computeResult.ComputeSpec.CPU*0.8, computeResult.ComputeSpec.Memory,
 		computeResult.ComputeSpec.CPU, computeResult.ComputeSpec.Memory,
 		portString,
+		probesString(timedResults["probes"].ProbeSpec),
+		containerSecurityString(security),
+		envFromString(config),
 		networkResult.NetworkSpec.Bandwidth,
 		storageResult.StorageSpec.Capacity,
 		storageResult.StorageSpec.Class,
+		envString(computeResult.ComputeSpec.Env),
+		envRefsString(config.EnvRefs),
+		tmpVolumeMountString(security),
+		extraContainersString(config, computeResult.ComputeSpec, security, "sidecar"),
+		appName,
+		tmpVolumeString(security),
 	)
 	return manifest
 }
 
-// Generates and writes the Kubernetes manifest file
-func (m *model) generateAndWriteManifest() error {
+// Generates the PersistentVolumeClaim holding the app's data, annotated with
+// the month the forecast expects it to need resizing, as the report states
+// it, and the months from generation until then
+func generatePersistentVolumeClaim(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	storage := timedResults["storage"].StorageSpec
+
+	annotations := ""
+	if storage.Forecast != nil {
+		fillDate, fillMonths := "none", "none"
+		if !storage.Forecast.FillDate.IsZero() {
+			fillDate = storage.Forecast.FillDate.Format("2006-01")
+			fillMonths = strconv.Itoa(storage.Forecast.FillMonths)
+		}
+		annotations = fmt.Sprintf(`
+  annotations:
+    %sfill-threshold: "%.0f%%"
+    %sfill-threshold-date: %q
+    %sfill-threshold-months: %q`, specAnnotationPrefix, fillThreshold*100, specAnnotationPrefix, fillDate, specAnnotationPrefix, fillMonths)
+	}
+
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: %s-data%s
+spec:
+  accessModes:
+    - %s
+  storageClassName: %s
+  resources:
+    requests:
+      storage: %s
+`, config.AppName, annotations, storage.AccessMode, storage.Class, storage.Capacity)
+}
+
+// Renders extra container env vars in the layout of the env list
+func envString(env []EnvVar) string {
+	var sb strings.Builder
+	for _, v := range env {
+		sb.WriteString(fmt.Sprintf("          - name: %s\n            value: %q\n", v.Name, v.Value))
+	}
+	return sb.String()
+}
+
+// Generates and writes the Kubernetes manifest files
+func (m *model) generateAndWriteManifest(mode writeMode) error {
 	if m.result == nil {
 		return fmt.Errorf("no results available to generate manifest")
 	}
 
-	k8sManifest := generateKubernetesManifest(m.config.AppName, m.result, m.result["network"].NetworkSpec.Ports)
-	outputPath := filepath.Join("k8s", fmt.Sprintf("%s-deployment.yaml", m.config.AppName))
+	paths, notMerged, err := writeManifests(generateManifests(&m.config, m.result), mode)
+	m.k8sManifestPaths = paths
+	m.notMergedManifests = notMerged
+	return err
+}
 
-	// Create the "k8s" directory if it doesn't exist
-	err := os.MkdirAll("k8s", 0755)
+// Asks before clobbering manifests that were changed since generation,
+// writes right away if there are none
+func (m *model) checkConflicts() tea.Cmd {
+	conflicts, err := findManifestConflicts(generateManifests(&m.config, m.result))
 	if err != nil {
-		return fmt.Errorf("error creating k8s directory: %v", err)
+		m.err = fmt.Errorf("failed to check existing manifests: %v", err)
+		m.inputState = inputStateDone
+		return tea.Quit
 	}
+	if len(conflicts) > 0 {
+		m.conflicts = conflicts
+		m.inputState = inputStateConfirmOverwrite
+		m.showConflictDiff()
+		return nil
+	}
+	return m.finishWrite(writeModeOverwrite)
+}
+
+// Handles the keys of the prompt shown when the generated objects fail
+// validation or break a policy of error severity
+func (m model) updateConfirmViolations(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "y":
+		cmd = m.checkConflicts()
+	case "a", "esc":
+		m.inputState = inputStateDone
+		m.output = "# Aborted\n\nNo manifests were written, " + m.blockingFindings() + ".\n"
+		cmd = tea.Quit
+	}
+	return m, cmd
+}
 
-	// Write the manifest to the specified file
-	err = os.WriteFile(outputPath, []byte(k8sManifest), 0644)
+// Renders the blocking findings and the available choices
+func (m model) confirmViolationsView() string {
+	findings := "# Generated Manifests Have Problems\n" + m.findingsMarkdown()
+	rendered, err := renderMarkdown(findings, max(40, m.width-4))
 	if err != nil {
-		return fmt.Errorf("error writing Kubernetes manifest: %v", err)
+		rendered = findings
+	}
+	return rendered + "\nPress y to write them anyway, a to abort.\n"
+}
+
+// Writes the manifests, renders the final output and quits
+func (m *model) finishWrite(mode writeMode) tea.Cmd {
+	m.inputState = inputStateDone
+
+	// Generate and write Kubernetes manifest. They were validated when
+	// processing completed and blocking findings confirmed by then.
+	if err := m.generateAndWriteManifest(mode); err != nil {
+		m.err = fmt.Errorf("failed to generate/write manifest: %v", err)
 	}
 
-	m.k8sManifestPath = outputPath
-	return nil
+	// Generate output string with Glamour
+	m.output = m.generateOutput()
+
+	return tea.Quit // Quit after processing is done and output is generated
 }
 
 // Generates the final output string in Markdown format for Glamour
@@ -673,37 +1139,171 @@ func (m *model) generateOutput() string {
 	var sb strings.Builder
 
 	sb.WriteString("# Resource Allocation Decision\n\n")
+	sb.WriteString(summaryMarkdown(m.result))
+	if !m.findingsShown {
+		sb.WriteString(m.findingsMarkdown())
+	}
+
+	if len(m.k8sManifestPaths) > 0 {
+		sb.WriteString("\n## Files Generated\n\n")
+		for _, path := range m.k8sManifestPaths {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", path))
+		}
+	}
+
+	if len(m.notMergedManifests) > 0 {
+		sb.WriteString("\n## Not Merged\n\n")
+		sb.WriteString("These existing files were left unchanged, overwrite them to apply the decisions:\n\n")
+		for _, skipped := range m.notMergedManifests {
+			sb.WriteString(fmt.Sprintf("- %s\n", skipped))
+		}
+	}
+
+	return sb.String()
+}
+
+// Renders the decided resources as a Markdown section, shared by the final
+// output and the live preview
+func summaryMarkdown(timedResults map[string]TimedResult) string {
+	var sb strings.Builder
 
-	computeResult := m.result["compute"]
-	networkResult := m.result["network"]
-	storageResult := m.result["storage"]
+	computeResult := timedResults["compute"]
+	networkResult := timedResults["network"]
+	storageResult := timedResults["storage"]
 
 	sb.WriteString("## Decided Resources\n\n")
-	sb.WriteString(fmt.Sprintf("- **Compute:** CPU=%.2f cores, Memory=%s (took %s)\n",
+	imageResult := timedResults["image"]
+	sb.WriteString(fmt.Sprintf("- **Image:** %s (took %s)\n", imageResult.ImageSpec, imageResult.Duration))
+	if warning := imageResult.ImageSpec.Warning; warning != "" {
+		sb.WriteString(fmt.Sprintf("  - ⚠ %s\n", warning))
+	}
+	sb.WriteString(fmt.Sprintf("- **Compute:** CPU=%.2f cores, Memory=%s, Replicas=%d (took %s)\n",
 		computeResult.ComputeSpec.CPU,
 		computeResult.ComputeSpec.Memory,
+		max(1, computeResult.ComputeSpec.Replicas),
 		computeResult.Duration,
 	))
-	sb.WriteString(fmt.Sprintf("- **Network:** Bandwidth=%s, Ports=%v (took %s)\n",
+	if computeResult.ComputeSpec.Utilization > 0 {
+		sb.WriteString(fmt.Sprintf("  - Sized for %.0f%% CPU utilization with %.1f requests in flight per replica\n",
+			computeResult.ComputeSpec.Utilization*100,
+			computeResult.ComputeSpec.InFlight,
+		))
+	}
+	for _, v := range computeResult.ComputeSpec.Env {
+		sb.WriteString(fmt.Sprintf("  - Sets `%s=%s`\n", v.Name, v.Value))
+	}
+	if containers := computeResult.ComputeSpec.Containers; len(containers) > 0 {
+		for _, container := range containers {
+			sb.WriteString(fmt.Sprintf("  - %s `%s`: %s\n", containerRoleTitles[container.Role], container.Name, container))
+		}
+		sb.WriteString(fmt.Sprintf("  - Pod reserves CPU=%.2f cores, Memory=%.0fMi per replica\n",
+			computeResult.ComputeSpec.podCPU(),
+			computeResult.ComputeSpec.podMemoryMi(),
+		))
+	}
+	ports := make([]string, len(networkResult.NetworkSpec.Ports))
+	for i, port := range networkResult.NetworkSpec.Ports {
+		ports[i] = port.String()
+	}
+	sb.WriteString(fmt.Sprintf("- **Network:** Bandwidth=%s, Ports=%s (took %s)\n",
 		networkResult.NetworkSpec.Bandwidth,
-		networkResult.NetworkSpec.Ports,
+		strings.Join(ports, ", "),
 		networkResult.Duration,
 	))
+	if route := networkResult.NetworkSpec.Route; route != nil {
+		sb.WriteString(fmt.Sprintf("  - Exposed at %s through an %s\n", route.url(), route.Kind))
+	}
+	probesResult := timedResults["probes"]
+	if probes := probesResult.ProbeSpec; probes.Readiness != nil {
+		sb.WriteString(fmt.Sprintf("- **Probes:** Readiness=%s, Liveness=%s, Startup allows %ds (took %s)\n",
+			probes.Readiness,
+			probes.Liveness,
+			probes.Startup.PeriodSeconds*probes.Startup.FailureThreshold,
+			probesResult.Duration,
+		))
+	} else {
+		sb.WriteString(fmt.Sprintf("- **Probes:** None, the app has no TCP port to probe (took %s)\n", probesResult.Duration))
+	}
+	availabilityResult := timedResults["availability"]
+	sb.WriteString(fmt.Sprintf("- **Availability:** %s (took %s)\n",
+		availabilityResult.AvailabilitySpec,
+		availabilityResult.Duration,
+	))
+	priorityResult := timedResults["priority"]
+	sb.WriteString(fmt.Sprintf("- **Priority:** %s (took %s)\n",
+		priorityResult.PrioritySpec,
+		priorityResult.Duration,
+	))
+	if autoscalingResult := timedResults["autoscaling"]; autoscalingResult.AutoscalingSpec.Policy.UpdateMode != "" {
+		sb.WriteString(fmt.Sprintf("- **Autoscaling:** %s (took %s)\n",
+			autoscalingResult.AutoscalingSpec,
+			autoscalingResult.Duration,
+		))
+	}
 	sb.WriteString(fmt.Sprintf("- **Storage:** Capacity=%s, Class=%s (took %s)\n",
 		storageResult.StorageSpec.Capacity,
 		storageResult.StorageSpec.Class,
 		storageResult.Duration,
 	))
-
-	if m.k8sManifestPath != "" {
-		sb.WriteString(fmt.Sprintf("\n## File Generated\n\nDeployment file generated within `%s`\n", m.k8sManifestPath))
+	if storageResult.StorageSpec.MonthlyCost > 0 {
+		sb.WriteString(fmt.Sprintf("  - Cheapest class for %s, about $%.2f per month\n",
+			storageRequirements(storageResult.StorageSpec.IOPS, storageResult.StorageSpec.ThroughputMBps, storageResult.StorageSpec.AccessMode),
+			storageResult.StorageSpec.MonthlyCost,
+		))
+	}
+	if warning := storageResult.StorageSpec.Warning; warning != "" {
+		sb.WriteString(fmt.Sprintf("  - ⚠ %s\n", warning))
+	}
+	if forecast := storageResult.StorageSpec.Forecast; forecast != nil {
+		sb.WriteString(forecastMarkdown(forecast, storageResult.StorageSpec.Capacity))
 	}
+	sb.WriteString(securityMarkdown(timedResults["security"].SecuritySpec))
 
 	return sb.String()
 }
 
+// Subcommands run instead of the wizard, keyed by their first argument
+var subcommands = map[string]func(args []string) error{
+	"import":    runImport,
+	"rightsize": runRightsize,
+	"calibrate": runCalibrate,
+	"validate":  runValidate,
+	"namespace": runNamespace,
+}
+
 func main() {
-	p := tea.NewProgram(initialModel())
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Printf("AlloCAT error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	specPath := flag.String("spec", "", "Path to a YAML spec file; runs headless instead of the wizard")
+	force := flag.Bool("force", false, "Overwrite existing manifests that differ from the generated ones (headless only)")
+	mergePath := flag.String("merge", "", "Patch the decisions into this existing manifest file instead of regenerating (headless only)")
+	kubeVersion := flag.String("kube-version", "", "Kubernetes version to validate the manifests for, the platform config's or the newest bundled if unset (headless only)")
+	acceptViolations := flag.Bool("accept-violations", false, "Write manifests that fail schema validation or break an error severity policy (headless only)")
+	flag.Parse()
+
+	if *specPath != "" {
+		if err := runHeadless(*specPath, *force, *mergePath, *kubeVersion, *acceptViolations); err != nil {
+			fmt.Printf("AlloCAT error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	platform, err := loadPlatformConfig()
+	if err != nil {
+		fmt.Printf("AlloCAT error: %v\n", err)
+		os.Exit(1)
+	}
+
+	p := tea.NewProgram(initialModel(platform.Tiers))
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("AlloCAT error: %v\n", err)
 		os.Exit(1)
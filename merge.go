@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Container fields owned by the deciders. Everything else in a merged
+// container (image, volume mounts, ...) is left as it is.
+var mergedContainerFields = []string{"resources", "ports"}
+
+// Pod spec fields owned by the deciders
+var mergedPodFields = []string{"priorityClassName", "topologySpreadConstraints", "affinity"}
+
+// Container fields only added where the container has none, so hand-tuned
+// probes survive
+var defaultedContainerFields = []string{"startupProbe", "livenessProbe", "readinessProbe"}
+
+// Patches the decided fields of one generated document into its existing
+// counterpart, keeping every other field, comment and key order
+type documentMerger func(existing, generated *yaml.Node, doc manifestDocument) error
+
+// Kinds that can be merged into existing manifests
+var documentMergers = map[string]documentMerger{
+	"Deployment": mergeDeployment,
+}
+
+// Kinds appended when the stream lacks them, since the merged Deployment's
+// priority and replica count rely on them
+var companionKinds = []string{"PriorityClass", "PodDisruptionBudget"}
+
+// mergeReport lists the generated documents a merge did not patch in.
+type mergeReport struct {
+	Existing int      // Documents the stream had, the added ones follow them
+	Added    []string // Companion documents appended to the stream
+	Skipped  []string // Documents left out, with the reason
+}
+
+// Merges generated documents into a multi-document YAML stream. Documents are
+// matched by kind and name; missing companion documents are appended when the
+// stream holds the app's Deployment, other generated documents without a
+// match or without a merger are reported as skipped and the stream is left
+// unchanged for them.
+func mergeManifests(existing string, docs []manifestDocument) (string, mergeReport, error) {
+	var report mergeReport
+	var nodes []*yaml.Node
+	decoder := yaml.NewDecoder(strings.NewReader(existing))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", report, fmt.Errorf("error parsing existing manifest: %v", err)
+		}
+		nodes = append(nodes, &node)
+	}
+
+	// Companions belong next to the app's Deployment, not in an unrelated stream
+	hasDeployment := slices.ContainsFunc(docs, func(doc manifestDocument) bool {
+		return doc.Kind == "Deployment" && findDocument(nodes, doc) != nil
+	})
+
+	report.Existing = len(nodes)
+	for _, doc := range docs {
+		target := findDocument(nodes, doc)
+		merge, ok := documentMergers[doc.Kind]
+		companion := slices.Contains(companionKinds, doc.Kind)
+		if !ok && companion && target != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s (already present, left unchanged)", doc.Kind, doc.Name))
+			continue
+		}
+		if !ok && companion && !hasDeployment {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s (no matching Deployment to add it to)", doc.Kind, doc.Name))
+			continue
+		}
+		if !ok && !companion {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s (merging not supported)", doc.Kind, doc.Name))
+			continue
+		}
+		if ok && target == nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s %s (not found)", doc.Kind, doc.Name))
+			continue
+		}
+
+		var generated yaml.Node
+		if err := yaml.Unmarshal([]byte(doc.Content), &generated); err != nil {
+			return "", report, fmt.Errorf("error parsing generated %s: %v", doc.Kind, err)
+		}
+		if target == nil {
+			nodes = append(nodes, &generated)
+			report.Added = append(report.Added, fmt.Sprintf("%s %s", doc.Kind, doc.Name))
+			continue
+		}
+		if err := merge(documentRoot(target), documentRoot(&generated), doc); err != nil {
+			return "", report, fmt.Errorf("error merging %s %s: %v", doc.Kind, doc.Name, err)
+		}
+	}
+
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	for _, node := range nodes {
+		if err := encoder.Encode(node); err != nil {
+			return "", report, fmt.Errorf("error encoding merged manifest: %v", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", report, fmt.Errorf("error encoding merged manifest: %v", err)
+	}
+	return b.String(), report, nil
+}
+
+// Reads a manifest file, merges the generated documents into it and writes
+// it back in place
+func mergeIntoFile(path string, docs []manifestDocument) (mergeReport, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return mergeReport{}, fmt.Errorf("error reading manifest to merge into: %v", err)
+	}
+
+	merged, report, err := mergeManifests(string(existing), docs)
+	if err != nil {
+		return report, fmt.Errorf("%s: %v", path, err)
+	}
+	// Nothing was patched in, which is always the case without a matching
+	// Deployment, so keep the file byte for byte
+	if len(report.Skipped) == len(docs) {
+		return report, nil
+	}
+
+	err = os.WriteFile(path, []byte(merged), 0644)
+	if err != nil {
+		return report, fmt.Errorf("error writing merged manifest: %v", err)
+	}
+	return report, nil
+}
+
+// Finds the existing document with the generated document's kind and name.
+// Names without the "-deployment" style suffix match too, since hand-written
+// manifests are usually named after the app.
+func findDocument(nodes []*yaml.Node, doc manifestDocument) *yaml.Node {
+	shortName := strings.TrimSuffix(doc.Name, "-"+strings.ToLower(doc.Kind))
+	for _, node := range nodes {
+		root := documentRoot(node)
+		if scalarValue(mappingValue(root, "kind")) != doc.Kind {
+			continue
+		}
+		name := scalarValue(mappingValue(mappingValue(root, "metadata"), "name"))
+		if name == doc.Name || name == shortName {
+			return node
+		}
+	}
+	return nil
+}
+
+// Patches the replica count, the bandwidth annotations, priority and
+// scheduling constraints of the pod template and the resources,
+// ports and decision env vars of the matching container and the resources of
+// declared sidecars, adding probes it lacks and leaving images, existing
+// probes and other containers untouched
+func mergeDeployment(existing, generated *yaml.Node, doc manifestDocument) error {
+	generatedContainers := mappingValue(podSpec(generated), "containers")
+	if generatedContainers == nil || len(generatedContainers.Content) == 0 {
+		return fmt.Errorf("generated manifest has no containers")
+	}
+	generatedContainer := generatedContainers.Content[0]
+
+	existingContainers := mappingValue(podSpec(existing), "containers")
+	if existingContainers == nil || existingContainers.Kind != yaml.SequenceNode {
+		return fmt.Errorf("existing manifest has no containers")
+	}
+	container := findContainer(existingContainers, scalarValue(mappingValue(generatedContainer, "name")))
+	if container == nil {
+		return fmt.Errorf("no container matching %s found", scalarValue(mappingValue(generatedContainer, "name")))
+	}
+
+	// Bandwidth annotations are merged one by one so other pod annotations survive
+	if generatedAnnotations := mappingValue(templateMetadata(generated), "annotations"); generatedAnnotations != nil {
+		metadata := templateMetadata(existing)
+		if metadata == nil {
+			return fmt.Errorf("existing manifest has no pod template metadata")
+		}
+		annotations := mappingValue(metadata, "annotations")
+		if annotations == nil || annotations.Kind != yaml.MappingNode {
+			setMappingValue(metadata, "annotations", generatedAnnotations)
+		} else {
+			for i := 0; i+1 < len(generatedAnnotations.Content); i += 2 {
+				setMappingValue(annotations, generatedAnnotations.Content[i].Value, generatedAnnotations.Content[i+1])
+			}
+		}
+	}
+
+	// The disruption budget and availability minimum rely on the replica count
+	if replicas := mappingValue(mappingValue(generated, "spec"), "replicas"); replicas != nil {
+		setMappingValue(mappingValue(existing, "spec"), "replicas", replicas)
+	}
+
+	for _, field := range mergedPodFields {
+		if value := mappingValue(podSpec(generated), field); value != nil {
+			setMappingValue(podSpec(existing), field, value)
+		}
+	}
+
+	for _, field := range mergedContainerFields {
+		if value := mappingValue(generatedContainer, field); value != nil {
+			setMappingValue(container, field, value)
+		}
+	}
+
+	// Declared sidecars and init containers get their resources patched
+	// where the existing manifest already runs them
+	for _, list := range []string{"containers", "initContainers"} {
+		generatedList, existingList := mappingValue(podSpec(generated), list), mappingValue(podSpec(existing), list)
+		if generatedList == nil || existingList == nil {
+			continue
+		}
+		for _, extra := range generatedList.Content {
+			if extra == generatedContainer {
+				continue
+			}
+			name := scalarValue(mappingValue(extra, "name"))
+			for _, candidate := range existingList.Content {
+				if scalarValue(mappingValue(candidate, "name")) == name {
+					setMappingValue(candidate, "resources", mappingValue(extra, "resources"))
+				}
+			}
+		}
+	}
+
+	for _, field := range defaultedContainerFields {
+		if value := mappingValue(generatedContainer, field); value != nil && mappingValue(container, field) == nil {
+			setMappingValue(container, field, value)
+		}
+	}
+
+	// Env vars are merged one by one so the app's own variables survive
+	if generatedEnv := mappingValue(generatedContainer, "env"); generatedEnv != nil {
+		env := mappingValue(container, "env")
+		if env == nil {
+			setMappingValue(container, "env", generatedEnv)
+			return nil
+		}
+		for _, variable := range generatedEnv.Content {
+			upsertNamed(env, variable)
+		}
+	}
+	return nil
+}
+
+// Finds a container by its generated name or the app name, falling back to
+// the only container of single-container pods
+func findContainer(containers *yaml.Node, name string) *yaml.Node {
+	appName := strings.TrimSuffix(name, "-container")
+	for _, container := range containers.Content {
+		containerName := scalarValue(mappingValue(container, "name"))
+		if containerName == name || containerName == appName {
+			return container
+		}
+	}
+	if len(containers.Content) == 1 {
+		return containers.Content[0]
+	}
+	return nil
+}
+
+// Returns the pod spec inside a Deployment's template
+func podSpec(deployment *yaml.Node) *yaml.Node {
+	return mappingValue(mappingValue(mappingValue(deployment, "spec"), "template"), "spec")
+}
+
+// Returns the pod template metadata inside a Deployment
+func templateMetadata(deployment *yaml.Node) *yaml.Node {
+	return mappingValue(mappingValue(mappingValue(deployment, "spec"), "template"), "metadata")
+}
+
+// Unwraps a document node to its root mapping
+func documentRoot(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return node.Content[0]
+	}
+	return node
+}
+
+// Looks up a key in a mapping node, returning nil if either is missing
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Replaces the value of a key in place, or appends the key if it is missing
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+// Replaces the sequence entry with the same name, or appends the entry
+func upsertNamed(sequence, entry *yaml.Node) {
+	name := scalarValue(mappingValue(entry, "name"))
+	for i, existing := range sequence.Content {
+		if scalarValue(mappingValue(existing, "name")) == name {
+			sequence.Content[i] = entry
+			return
+		}
+	}
+	sequence.Content = append(sequence.Content, entry)
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const existingDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: registry.example.com/web:1.4 # pinned by release
+          resources:
+            limits:
+              cpu: "9"
+`
+
+const generatedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-deployment
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web-container
+          image: your-app-image:latest
+          resources:
+            limits:
+              cpu: "0.50"
+`
+
+func TestMergeManifests(t *testing.T) {
+	deployment := manifestDocument{Kind: "Deployment", Name: "web-deployment", Content: generatedDeployment}
+	priorityClass := manifestDocument{Kind: "PriorityClass", Name: "high-priority", Content: "apiVersion: scheduling.k8s.io/v1\nkind: PriorityClass\nmetadata:\n  name: high-priority\nvalue: 1000000\n"}
+	tests := []struct {
+		name        string
+		existing    string
+		docs        []manifestDocument
+		wantSkipped []string
+		wantAdded   []string
+		contains    []string // Substrings the merged stream must keep or gain
+		excludes    []string // Substrings the merge must replace
+	}{
+		{
+			name:     "resources patched, image and comments kept",
+			existing: existingDeployment,
+			docs:     []manifestDocument{deployment},
+			contains: []string{`cpu: "0.50"`, "replicas: 3", "registry.example.com/web:1.4 # pinned by release"},
+			excludes: []string{`cpu: "9"`, "replicas: 1", "your-app-image"},
+		},
+		{
+			name:      "missing companions are appended",
+			existing:  existingDeployment,
+			docs:      []manifestDocument{deployment, priorityClass},
+			wantAdded: []string{"PriorityClass high-priority"},
+			contains:  []string{"kind: PriorityClass", "value: 1000000"},
+		},
+		{
+			name:        "present companions are left unchanged",
+			existing:    existingDeployment + "---\napiVersion: scheduling.k8s.io/v1\nkind: PriorityClass\nmetadata:\n  name: high-priority\nvalue: 5\n",
+			docs:        []manifestDocument{deployment, priorityClass},
+			wantSkipped: []string{"PriorityClass high-priority (already present, left unchanged)"},
+			contains:    []string{"value: 5"},
+			excludes:    []string{"value: 1000000"},
+		},
+		{
+			name:        "unsupported kinds are skipped",
+			existing:    existingDeployment,
+			docs:        []manifestDocument{{Kind: "Service", Name: "web-service"}},
+			wantSkipped: []string{"Service web-service (merging not supported)"},
+			contains:    []string{`cpu: "9"`},
+		},
+		{
+			name:     "missing targets are skipped along with their companions",
+			existing: strings.Replace(existingDeployment, "name: web\n", "name: api\n", 1),
+			docs:     []manifestDocument{deployment, priorityClass},
+			wantSkipped: []string{
+				"Deployment web-deployment (not found)",
+				"PriorityClass high-priority (no matching Deployment to add it to)",
+			},
+			contains: []string{`cpu: "9"`},
+			excludes: []string{"kind: PriorityClass"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, report, err := mergeManifests(test.existing, test.docs)
+			if err != nil {
+				t.Fatalf("mergeManifests: %v", err)
+			}
+			if !slices.Equal(report.Skipped, test.wantSkipped) {
+				t.Errorf("skipped = %q, want %q", report.Skipped, test.wantSkipped)
+			}
+			if !slices.Equal(report.Added, test.wantAdded) {
+				t.Errorf("added = %q, want %q", report.Added, test.wantAdded)
+			}
+			for _, s := range test.contains {
+				if !strings.Contains(merged, s) {
+					t.Errorf("merged manifest is missing %q:\n%s", s, merged)
+				}
+			}
+			for _, s := range test.excludes {
+				if strings.Contains(merged, s) {
+					t.Errorf("merged manifest still contains %q:\n%s", s, merged)
+				}
+			}
+		})
+	}
+
+	if _, _, err := mergeManifests("kind: [", []manifestDocument{deployment}); err == nil {
+		t.Error("mergeManifests accepted an existing manifest that does not parse")
+	}
+}
+
+func TestMergeIntoFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// Unusual formatting shows whether the file was rewritten
+	existing := "kind:   Service\nmetadata: {name: web}\n"
+	if err := os.WriteFile("web.yaml", []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deployment := manifestDocument{Kind: "Deployment", Name: "web-deployment", Content: generatedDeployment}
+	priorityClass := manifestDocument{Kind: "PriorityClass", Name: "high-priority", Content: "kind: PriorityClass\nmetadata:\n  name: high-priority\n"}
+	report, err := mergeIntoFile("web.yaml", []manifestDocument{deployment, priorityClass})
+	if err != nil {
+		t.Fatalf("mergeIntoFile: %v", err)
+	}
+	if len(report.Skipped) != 2 || len(report.Added) != 0 {
+		t.Errorf("skipped %q and added %q, want both documents skipped", report.Skipped, report.Added)
+	}
+	if data, _ := os.ReadFile("web.yaml"); string(data) != existing {
+		t.Errorf("file was rewritten with nothing merged:\n%s", data)
+	}
+}
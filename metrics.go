@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// usageSamples holds observed usage, one value per sample: CPU in cores,
+// memory in Mi and requests per second across the whole app.
+type usageSamples struct {
+	CPU    []float64
+	Memory []float64
+	RPS    []float64
+	Pods   int // Pods the CPU samples came from, 0 if unknown
+}
+
+// usageStats summarises one metric's samples.
+type usageStats struct {
+	P50, P95, P99, Max float64
+	Count              int
+}
+
+// promResponse is the JSON returned by the Prometheus query API.
+type promResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Values [][2]any `json:"values"` // Range queries
+			Value  [2]any   `json:"value"`  // Instant queries
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Entry point of the rightsize subcommand: derives a ConfigSpec and compute
+// recommendation from observed usage instead of the expected load guess
+func runRightsize(args []string) error {
+	flags := flag.NewFlagSet("rightsize", flag.ExitOnError)
+	appName := flags.String("app", "", "Name of the app being right-sized")
+	csvPath := flags.String("csv", "", "CSV of samples with cpu (cores), memory (bytes or quantity) and rps columns")
+	cpuPath := flags.String("cpu", "", "Prometheus range-query JSON of per-pod CPU usage in cores")
+	memoryPath := flags.String("memory", "", "Prometheus range-query JSON of per-pod memory usage in bytes")
+	rpsPath := flags.String("rps", "", "Prometheus range-query JSON of the app's total requests per second")
+	manifestPath := flags.String("manifest", "", "Deployment manifest holding the current allocation (defaults to the deciders' allocation)")
+	headroom := flags.Float64("headroom", 0.2, "Headroom added on top of the p99 usage")
+	replicas := flags.Int("replicas", 0, "Pods serving the observed requests, the number of CPU series or 1 if unset")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-workloads rightsize -app name (-csv samples.csv | -cpu cpu.json -memory memory.json -rps rps.json) [-manifest deployment.yaml]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *appName == "" {
+		flags.Usage()
+		return fmt.Errorf("-app is required")
+	}
+
+	var samples usageSamples
+	var err error
+	if *csvPath != "" {
+		samples, err = readUsageCSV(*csvPath)
+	} else {
+		samples, err = readUsagePrometheus(*cpuPath, *memoryPath, *rpsPath)
+	}
+	if err != nil {
+		return err
+	}
+	if len(samples.CPU) == 0 && len(samples.Memory) == 0 && len(samples.RPS) == 0 {
+		flags.Usage()
+		return fmt.Errorf("no usage samples found")
+	}
+
+	// The current allocation comes from the manifest, or from what the deciders
+	// would allocate for the observed load
+	current := importedWorkload{Name: *appName, Config: ConfigSpec{AppName: *appName, ImportanceLevel: "medium"}}
+	if *manifestPath != "" {
+		current, err = findImportedWorkload(*manifestPath, *appName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *replicas > 0 {
+		samples.Pods = *replicas
+	}
+
+	config := current.Config
+	config.applyUsage(samples)
+
+	timedResults, err := collectTimedResourceSpecs(&config)
+	if err != nil {
+		return err
+	}
+	if *manifestPath == "" {
+		compute := timedResults["compute"].ComputeSpec
+		current.CPURequest = compute.CPU * 0.8
+		current.CPULimit = compute.CPU
+		current.MemoryLimit, _ = parseMemoryMi(compute.Memory)
+	}
+
+	printMarkdown(rightsizeMarkdown(config, samples, current, timedResults, *headroom, *manifestPath != ""))
+	return nil
+}
+
+// Derives the load, throughput per core and peak memory from the samples,
+// as a calibration would measure them. CPU samples are per pod, so the
+// app's cores are the per-pod p95 times the pods.
+func (config *ConfigSpec) applyUsage(samples usageSamples) {
+	cpu, memory, rps := summarise(samples.CPU), summarise(samples.Memory), summarise(samples.RPS)
+	if rps.Count > 0 {
+		config.ExpectedLoad = int(math.Ceil(rps.P95))
+	}
+	if rps.Count > 0 && cpu.P95 > 0 {
+		cores := cpu.P95 * float64(max(1, samples.Pods))
+		config.RPSPerCore = math.Round(rps.P95/cores*10) / 10
+	}
+	if memory.Count > 0 {
+		config.MeasuredMemoryMi = int(math.Ceil(memory.Max))
+	}
+}
+
+// Finds the Deployment for an app in a manifest file
+func findImportedWorkload(path, appName string) (importedWorkload, error) {
+	objects, err := readImportObjects(path)
+	if err != nil {
+		return importedWorkload{}, err
+	}
+	workloads, err := importWorkloads(objects)
+	if err != nil {
+		return importedWorkload{}, err
+	}
+	for _, workload := range workloads {
+		if workload.Name == appName || workload.Name == appName+"-deployment" {
+			return workload, nil
+		}
+	}
+	return importedWorkload{}, fmt.Errorf("no Deployment for %s found in %s", appName, path)
+}
+
+// Reads samples from a CSV file with a header naming the cpu, memory and rps
+// columns. Other columns, such as timestamps, are ignored.
+func readUsageCSV(path string) (usageSamples, error) {
+	var samples usageSamples
+
+	file, err := os.Open(path)
+	if err != nil {
+		return samples, fmt.Errorf("error reading metrics: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return samples, fmt.Errorf("error reading CSV header of %s: %v", path, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return samples, fmt.Errorf("error reading %s: %v", path, err)
+		}
+
+		field := func(name string) (string, bool) {
+			i, ok := columns[name]
+			if !ok || i >= len(record) || strings.TrimSpace(record[i]) == "" {
+				return "", false
+			}
+			return strings.TrimSpace(record[i]), true
+		}
+		if value, ok := field("cpu"); ok {
+			cpu, err := parseCPU(value)
+			if err != nil {
+				return samples, fmt.Errorf("%s line %d: %v", path, line, err)
+			}
+			samples.CPU = append(samples.CPU, cpu)
+		}
+		if value, ok := field("memory"); ok {
+			memory, err := parseMemoryMi(value)
+			if err != nil {
+				return samples, fmt.Errorf("%s line %d: %v", path, line, err)
+			}
+			samples.Memory = append(samples.Memory, memory)
+		}
+		if value, ok := field("rps"); ok {
+			rps, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return samples, fmt.Errorf("%s line %d: invalid rps %q", path, line, value)
+			}
+			samples.RPS = append(samples.RPS, rps)
+		}
+	}
+	return samples, nil
+}
+
+// Reads samples from Prometheus query results. CPU and memory series are
+// per pod and pooled; RPS series are summed per timestamp into app totals.
+func readUsagePrometheus(cpuPath, memoryPath, rpsPath string) (usageSamples, error) {
+	var samples usageSamples
+	if cpuPath != "" {
+		series, err := readPromSeries(cpuPath)
+		if err != nil {
+			return samples, err
+		}
+		samples.Pods = len(series)
+		for _, values := range series {
+			for _, value := range values {
+				samples.CPU = append(samples.CPU, value)
+			}
+		}
+	}
+	if memoryPath != "" {
+		series, err := readPromSeries(memoryPath)
+		if err != nil {
+			return samples, err
+		}
+		for _, values := range series {
+			for _, value := range values {
+				samples.Memory = append(samples.Memory, value/(1<<20))
+			}
+		}
+	}
+	if rpsPath != "" {
+		series, err := readPromSeries(rpsPath)
+		if err != nil {
+			return samples, err
+		}
+		totals := make(map[float64]float64)
+		for _, values := range series {
+			for timestamp, value := range values {
+				totals[timestamp] += value
+			}
+		}
+		for _, total := range totals {
+			samples.RPS = append(samples.RPS, total)
+		}
+	}
+	return samples, nil
+}
+
+// Reads a Prometheus query result into one timestamp to value map per series
+func readPromSeries(path string) ([]map[float64]float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading metrics: %v", err)
+	}
+
+	var response promResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("error parsing Prometheus response %s: %v", path, err)
+	}
+	if response.Status != "" && response.Status != "success" {
+		return nil, fmt.Errorf("Prometheus response %s has status %q", path, response.Status)
+	}
+
+	var series []map[float64]float64
+	for _, result := range response.Data.Result {
+		points := result.Values
+		if response.Data.ResultType == "vector" {
+			points = [][2]any{result.Value}
+		}
+
+		values := make(map[float64]float64)
+		for _, point := range points {
+			timestamp, ok := point[0].(float64)
+			text, isText := point[1].(string)
+			if !ok || !isText {
+				return nil, fmt.Errorf("unexpected sample %v in %s", point, path)
+			}
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sample value %q in %s", text, path)
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue // Gaps in rate() results
+			}
+			values[timestamp] = value
+		}
+		series = append(series, values)
+	}
+	return series, nil
+}
+
+// Computes nearest-rank percentiles of the samples
+func summarise(samples []float64) usageStats {
+	if len(samples) == 0 {
+		return usageStats{}
+	}
+	sorted := append([]float64(nil), samples...)
+	sort.Float64s(sorted)
+
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return sorted[max(0, rank)]
+	}
+	return usageStats{
+		P50:   percentile(50),
+		P95:   percentile(95),
+		P99:   percentile(99),
+		Max:   sorted[len(sorted)-1],
+		Count: len(sorted),
+	}
+}
+
+// Renders the observed usage, the usage-based recommendation compared with
+// the current allocation, and the derived spec
+func rightsizeMarkdown(config ConfigSpec, samples usageSamples, current importedWorkload, timedResults map[string]TimedResult, headroom float64, fromManifest bool) string {
+	var sb strings.Builder
+
+	cpu := summarise(samples.CPU)
+	memory := summarise(samples.Memory)
+	rps := summarise(samples.RPS)
+
+	sb.WriteString(fmt.Sprintf("# Right-Sizing Report: %s\n\n", config.AppName))
+	sb.WriteString("## Observed Usage\n\n")
+	sb.WriteString("| Metric | Samples | p50 | p95 | p99 | Max |\n")
+	sb.WriteString("|---|---|---|---|---|---|\n")
+	for _, row := range []struct {
+		name   string
+		stats  usageStats
+		format string
+	}{
+		{"CPU (cores)", cpu, "%.3f"},
+		{"Memory (Mi)", memory, "%.0f"},
+		{"Requests/s", rps, "%.1f"},
+	} {
+		if row.stats.Count == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %d | %s | %s | %s | %s |\n", row.name, row.stats.Count,
+			fmt.Sprintf(row.format, row.stats.P50), fmt.Sprintf(row.format, row.stats.P95),
+			fmt.Sprintf(row.format, row.stats.P99), fmt.Sprintf(row.format, row.stats.Max)))
+	}
+
+	source := "what the deciders allocate for the observed load"
+	if fromManifest {
+		source = "the manifest"
+	}
+	sb.WriteString(fmt.Sprintf("\n## Recommendation\n\nRequests at p95, limits at p99 plus %.0f%% headroom, compared with %s.\n\n", headroom*100, source))
+	sb.WriteString("| Resource | Current | Recommended | Status | Peak Utilization |\n")
+	sb.WriteString("|---|---|---|---|---|\n")
+
+	utilization := func(peak, allocated float64) string {
+		if allocated == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", peak/allocated*100)
+	}
+	if cpu.Count > 0 {
+		request := numericFinding("CPU request", current.CPURequest, cpu.P95, 0.1, "%.2f cores")
+		limit := numericFinding("CPU limit", current.CPULimit, cpu.P99*(1+headroom), 0.1, "%.2f cores")
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", request.Resource, request.Current, request.Recommended, request.Status, utilization(cpu.P95, current.CPURequest)))
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", limit.Resource, limit.Current, limit.Recommended, limit.Status, utilization(cpu.Max, current.CPULimit)))
+	}
+	if memory.Count > 0 {
+		// Memory is not compressible, so size from the highest sample
+		recommended := math.Ceil(math.Max(memory.P99*(1+headroom), memory.Max)/16) * 16
+		limit := numericFinding("Memory limit", current.MemoryLimit, recommended, 0.1, "%.0fMi")
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n", limit.Resource, limit.Current, limit.Recommended, limit.Status, utilization(memory.Max, current.MemoryLimit)))
+	}
+
+	compute := timedResults["compute"].ComputeSpec
+	sb.WriteString(fmt.Sprintf("\n## Derived Spec\n\nExpected load set to the p95 request rate, throughput per core and peak memory measured from the samples. The deciders allocate CPU=%.2f cores, Memory=%s for it.\n\n", compute.CPU, compute.Memory))
+	specYAML, err := yaml.Marshal(config)
+	if err == nil {
+		sb.WriteString("```yaml\n" + string(specYAML) + "```\n")
+	}
+	return sb.String()
+}
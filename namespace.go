@@ -0,0 +1,328 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// namespaceApp is one app of a namespace plan with its decided allocation.
+type namespaceApp struct {
+	Name          string
+	Replicas      int
+	CPU           float64 // Pod CPU limit in cores, per replica
+	MemoryMi      float64 // Pod memory limit, per replica
+	StorageGi     float64
+	StorageClass  string
+	SecurityLevel string
+}
+
+// namespacePlan aggregates the allocations of a batch of apps sharing a
+// namespace into its quota and container limits.
+type namespacePlan struct {
+	Name     string
+	Labels   map[string]string
+	Headroom float64 // Fraction added on top of the batch's pods, CPU and memory
+	Apps     []namespaceApp
+
+	// Quota, headroom included except for storage
+	Pods      int
+	CPU       float64            // Limits in cores, requests are 80% of it
+	MemoryMi  float64            // Requests and limits
+	StorageGi map[string]float64 // By storage class
+
+	// Bounds of a single container and claim
+	MaxContainerCPU      float64
+	MaxContainerMemoryMi float64
+	MinContainerCPU      float64 // Default for containers declaring no resources
+	MinContainerMemoryMi float64
+	MaxClaimGi           float64
+
+	SecurityLevel string // Least strict level of the apps, so every app is admitted
+}
+
+// Entry point of the namespace subcommand: decides every spec of the batch
+// and writes the Namespace, ResourceQuota and LimitRange they fit into
+func runNamespace(args []string) error {
+	flags := flag.NewFlagSet("namespace", flag.ExitOnError)
+	name := flags.String("name", "", "Name of the namespace the apps share")
+	labels := flags.String("labels", "", "Namespace labels as key=value pairs, e.g. team=payments,env=prod")
+	headroom := flags.Float64("headroom", 0.25, "Headroom on top of the batch's pods, CPU and memory, 0.25 fits the pods a rolling update surges by default")
+	force := flags.Bool("force", false, "Overwrite existing manifests that differ from the generated ones")
+	kubeVersion := flags.String("kube-version", "", "Kubernetes version to validate the manifests for, the platform config's or the newest bundled if unset")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-workloads namespace -name namespace [-labels team=payments] [-headroom 0.25] [-force] spec.yaml...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *name == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("-name and at least one spec file are required")
+	}
+	if !dnsLabelPattern.MatchString(*name) || len(*name) > 63 {
+		return fmt.Errorf("namespace name %q must be a DNS label", *name)
+	}
+	if *headroom < 0 {
+		return fmt.Errorf("-headroom must not be negative")
+	}
+	namespaceLabels, err := parseLabels(*labels)
+	if err != nil {
+		return err
+	}
+
+	plan := namespacePlan{Name: *name, Labels: namespaceLabels, Headroom: *headroom}
+	for _, path := range flags.Args() {
+		config, err := loadSpec(path)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(plan.Apps, func(app namespaceApp) bool { return app.Name == config.AppName }) {
+			return fmt.Errorf("%s: app %s is already in the batch", path, config.AppName)
+		}
+		timedResults, err := collectTimedResourceSpecs(&config)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		plan.add(config.AppName, timedResults)
+	}
+	plan.aggregate()
+
+	docs := plan.manifests()
+	version, err := resolveKubernetesVersion(*kubeVersion)
+	if err != nil {
+		return err
+	}
+	var results []objectValidation
+	for _, doc := range docs {
+		docResults, err := validateManifest(doc.File, doc.Content, version)
+		if err != nil {
+			return err
+		}
+		results = append(results, docResults...)
+	}
+	if invalid := invalidObjects(results); invalid > 0 {
+		printMarkdown(validationMarkdown(results, version))
+		return fmt.Errorf("%d generated object(s) fail schema validation for Kubernetes %s", invalid, version)
+	}
+	if !*force {
+		conflicts, err := findManifestConflicts(docs)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			printMarkdown(conflictsMarkdown(conflicts, true))
+			return fmt.Errorf("%d existing manifest(s) differ from the generated ones, re-run with --force to overwrite", len(conflicts))
+		}
+	}
+
+	paths, _, err := writeManifests(docs, writeModeOverwrite)
+	if err != nil {
+		return err
+	}
+	printMarkdown(plan.markdown(paths))
+	return nil
+}
+
+// Adds an app with its decided resources to the plan
+func (plan *namespacePlan) add(appName string, timedResults map[string]TimedResult) {
+	compute := timedResults["compute"].ComputeSpec
+	storage := timedResults["storage"].StorageSpec
+	storageMi, _ := parseMemoryMi(storage.Capacity)
+	plan.Apps = append(plan.Apps, namespaceApp{
+		Name:          appName,
+		Replicas:      max(1, compute.Replicas),
+		CPU:           compute.podCPU(),
+		MemoryMi:      compute.podMemoryMi(),
+		StorageGi:     storageMi / 1024,
+		StorageClass:  storage.Class,
+		SecurityLevel: timedResults["security"].SecuritySpec.Level,
+	})
+
+	// The LimitRange has to admit every container the apps run
+	appMemoryMi, _ := parseMemoryMi(compute.Memory)
+	containers := []ContainerResources{{CPU: compute.CPU, Memory: fmt.Sprintf("%.0fMi", appMemoryMi)}}
+	containers = append(containers, compute.Containers...)
+	for _, container := range containers {
+		memoryMi, _ := parseMemoryMi(container.Memory)
+		plan.MaxContainerCPU = math.Max(plan.MaxContainerCPU, container.CPU)
+		plan.MaxContainerMemoryMi = math.Max(plan.MaxContainerMemoryMi, memoryMi)
+		if plan.MinContainerCPU == 0 || container.CPU < plan.MinContainerCPU {
+			plan.MinContainerCPU = container.CPU
+		}
+		if plan.MinContainerMemoryMi == 0 || memoryMi < plan.MinContainerMemoryMi {
+			plan.MinContainerMemoryMi = memoryMi
+		}
+	}
+	plan.MaxClaimGi = math.Max(plan.MaxClaimGi, storageMi/1024)
+}
+
+// Totals the apps times their replicas and adds the headroom. Storage gets
+// none: a rolling update surges pods, not claims.
+func (plan *namespacePlan) aggregate() {
+	scale := 1 + plan.Headroom
+	plan.StorageGi = make(map[string]float64)
+	replicas := 0
+	for _, app := range plan.Apps {
+		replicas += app.Replicas
+		plan.CPU += app.CPU * float64(app.Replicas) * scale
+		plan.MemoryMi += app.MemoryMi * float64(app.Replicas) * scale
+		plan.StorageGi[app.StorageClass] += app.StorageGi
+
+		// Every app has to be admitted, so the least strict level wins
+		if plan.SecurityLevel == "" || slices.Index(podSecurityLevels, app.SecurityLevel) < slices.Index(podSecurityLevels, plan.SecurityLevel) {
+			plan.SecurityLevel = app.SecurityLevel
+		}
+	}
+	plan.Pods = int(math.Ceil(float64(replicas) * scale))
+}
+
+// Generates the Namespace, ResourceQuota and LimitRange of the plan
+func (plan *namespacePlan) manifests() []manifestDocument {
+	return []manifestDocument{
+		{
+			Kind:    "Namespace",
+			Name:    plan.Name,
+			File:    fmt.Sprintf("namespace-%s.yaml", plan.Name),
+			Content: generateNamespace(plan),
+		},
+		{
+			Kind:    "ResourceQuota",
+			Name:    fmt.Sprintf("%s-quota", plan.Name),
+			File:    fmt.Sprintf("namespace-%s-resourcequota.yaml", plan.Name),
+			Content: generateResourceQuota(plan),
+		},
+		{
+			Kind:    "LimitRange",
+			Name:    fmt.Sprintf("%s-limits", plan.Name),
+			File:    fmt.Sprintf("namespace-%s-limitrange.yaml", plan.Name),
+			Content: generateLimitRange(plan),
+		},
+	}
+}
+
+// Generates the Namespace, enforcing the apps' Pod Security Standard
+func generateNamespace(plan *namespacePlan) string {
+	labels := maps.Clone(plan.Labels)
+	labels["pod-security.kubernetes.io/enforce"] = plan.SecurityLevel
+	var sb strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		sb.WriteString(fmt.Sprintf("    %s: %q\n", key, labels[key]))
+	}
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+  labels:
+%s`, plan.Name, sb.String())
+}
+
+// Generates the ResourceQuota capping the namespace at the batch's totals
+func generateResourceQuota(plan *namespacePlan) string {
+	var storage strings.Builder
+	totalGi := 0
+	for _, class := range slices.Sorted(maps.Keys(plan.StorageGi)) {
+		gi := int(math.Ceil(plan.StorageGi[class]))
+		totalGi += gi
+		storage.WriteString(fmt.Sprintf("    %s.storageclass.storage.k8s.io/requests.storage: \"%dGi\"\n", class, gi))
+	}
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: %s-quota
+  namespace: %s
+spec:
+  hard:
+    pods: "%d"
+    requests.cpu: "%.2f"
+    limits.cpu: "%.2f"
+    requests.memory: "%dMi"
+    limits.memory: "%dMi"
+    requests.storage: "%dGi"
+%s    persistentvolumeclaims: "%d"
+    services: "%d"
+`, plan.Name, plan.Name, plan.Pods,
+		ceilCores(plan.CPU*0.8), ceilCores(plan.CPU),
+		int(math.Ceil(plan.MemoryMi)), int(math.Ceil(plan.MemoryMi)),
+		totalGi, storage.String(), len(plan.Apps), len(plan.Apps))
+}
+
+// Generates the LimitRange bounding single containers, with headroom, and
+// claims by the largest of the batch, defaulting containers without
+// resources to the smallest
+func generateLimitRange(plan *namespacePlan) string {
+	scale := 1 + plan.Headroom
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: LimitRange
+metadata:
+  name: %s-limits
+  namespace: %s
+spec:
+  limits:
+    - type: Container
+      max:
+        cpu: "%.2f"
+        memory: "%dMi"
+      default:
+        cpu: "%.2f"
+        memory: "%dMi"
+      defaultRequest:
+        cpu: "%.2f"
+        memory: "%dMi"
+    - type: PersistentVolumeClaim
+      max:
+        storage: "%dGi"
+`, plan.Name, plan.Name,
+		ceilCores(plan.MaxContainerCPU*scale), int(math.Ceil(plan.MaxContainerMemoryMi*scale)),
+		ceilCores(plan.MinContainerCPU), int(math.Ceil(plan.MinContainerMemoryMi)),
+		ceilCores(plan.MinContainerCPU*0.8), int(math.Ceil(plan.MinContainerMemoryMi)),
+		int(math.Ceil(plan.MaxClaimGi)))
+}
+
+// Rounds cores up to the two decimals manifests carry, so totals never fall
+// short of what they add up
+func ceilCores(cpu float64) float64 {
+	return math.Ceil(cpu*100-1e-9) / 100
+}
+
+// Renders the plan as Markdown: every app, the totals and the written files
+func (plan *namespacePlan) markdown(paths []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Namespace Plan: %s\n\n", plan.Name))
+	sb.WriteString("| App | Replicas | CPU per pod | Memory per pod | Storage |\n")
+	sb.WriteString("|---|---|---|---|---|\n")
+	var cpu, memoryMi, storageGi float64
+	replicas := 0
+	for _, app := range plan.Apps {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %.2f cores | %.0fMi | %.0fGi %s |\n",
+			app.Name, app.Replicas, app.CPU, app.MemoryMi, app.StorageGi, app.StorageClass))
+		replicas += app.Replicas
+		cpu += app.CPU * float64(app.Replicas)
+		memoryMi += app.MemoryMi * float64(app.Replicas)
+		storageGi += app.StorageGi
+	}
+	sb.WriteString(fmt.Sprintf("| **Total** | %d | %.2f cores | %.0fMi | %.0fGi |\n", replicas, cpu, memoryMi, storageGi))
+
+	sb.WriteString(fmt.Sprintf("\n## Quota (+%.0f%% headroom on pods, CPU and memory)\n\n", plan.Headroom*100))
+	sb.WriteString(fmt.Sprintf("- **Pods:** %d\n", plan.Pods))
+	sb.WriteString(fmt.Sprintf("- **CPU:** %.2f cores of limits, %.2f of requests\n", ceilCores(plan.CPU), ceilCores(plan.CPU*0.8)))
+	sb.WriteString(fmt.Sprintf("- **Memory:** %.0fMi\n", math.Ceil(plan.MemoryMi)))
+	for _, class := range slices.Sorted(maps.Keys(plan.StorageGi)) {
+		sb.WriteString(fmt.Sprintf("- **Storage (%s):** %.0fGi\n", class, math.Ceil(plan.StorageGi[class])))
+	}
+	sb.WriteString(fmt.Sprintf("- **Containers:** at most %.2f cores and %.0fMi each, %.2f cores and %.0fMi when unset\n",
+		ceilCores(plan.MaxContainerCPU*(1+plan.Headroom)), math.Ceil(plan.MaxContainerMemoryMi*(1+plan.Headroom)),
+		ceilCores(plan.MinContainerCPU), math.Ceil(plan.MinContainerMemoryMi)))
+	sb.WriteString(fmt.Sprintf("- **Pod Security:** enforces %s\n", plan.SecurityLevel))
+
+	sb.WriteString("\n## Files Generated\n\n")
+	for _, path := range paths {
+		sb.WriteString(fmt.Sprintf("- `%s`\n", path))
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"maps"
+	"math"
+	"testing"
+)
+
+func TestNamespacePlanAggregate(t *testing.T) {
+	tests := []struct {
+		name         string
+		headroom     float64
+		apps         []namespaceApp
+		wantPods     int
+		wantCPU      float64
+		wantMemoryMi float64
+		wantStorage  map[string]float64
+		wantLevel    string
+	}{
+		{
+			name:         "single app without headroom",
+			apps:         []namespaceApp{{Replicas: 2, CPU: 0.5, MemoryMi: 256, StorageGi: 10, StorageClass: "standard", SecurityLevel: "restricted"}},
+			wantPods:     2,
+			wantCPU:      1,
+			wantMemoryMi: 512,
+			wantStorage:  map[string]float64{"standard": 10},
+			wantLevel:    "restricted",
+		},
+		{
+			name:     "headroom on pods, CPU and memory but not storage",
+			headroom: 0.25,
+			apps: []namespaceApp{
+				{Replicas: 3, CPU: 1, MemoryMi: 1024, StorageGi: 20, StorageClass: "shared", SecurityLevel: "restricted"},
+				{Replicas: 1, CPU: 0.5, MemoryMi: 512, StorageGi: 35, StorageClass: "premium", SecurityLevel: "baseline"},
+				{Replicas: 2, CPU: 0.25, MemoryMi: 256, StorageGi: 5, StorageClass: "premium", SecurityLevel: "restricted"},
+			},
+			wantPods:     8, // 6 pods plus 25%, rounded up
+			wantCPU:      4 * 1.25,
+			wantMemoryMi: 4096 * 1.25,
+			wantStorage:  map[string]float64{"shared": 20, "premium": 40},
+			wantLevel:    "baseline",
+		},
+		{
+			name: "least strict level wins",
+			apps: []namespaceApp{
+				{Replicas: 1, StorageClass: "standard", SecurityLevel: "restricted"},
+				{Replicas: 1, StorageClass: "standard", SecurityLevel: "privileged"},
+				{Replicas: 1, StorageClass: "standard", SecurityLevel: "baseline"},
+			},
+			wantPods:    3,
+			wantStorage: map[string]float64{"standard": 0},
+			wantLevel:   "privileged",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan := namespacePlan{Name: "shop", Headroom: test.headroom, Apps: test.apps}
+			plan.aggregate()
+			if plan.Pods != test.wantPods {
+				t.Errorf("pods = %d, want %d", plan.Pods, test.wantPods)
+			}
+			if math.Abs(plan.CPU-test.wantCPU) > 1e-9 {
+				t.Errorf("CPU = %.2f, want %.2f", plan.CPU, test.wantCPU)
+			}
+			if math.Abs(plan.MemoryMi-test.wantMemoryMi) > 1e-9 {
+				t.Errorf("memory = %.0fMi, want %.0fMi", plan.MemoryMi, test.wantMemoryMi)
+			}
+			if !maps.Equal(plan.StorageGi, test.wantStorage) {
+				t.Errorf("storage = %v, want %v", plan.StorageGi, test.wantStorage)
+			}
+			if plan.SecurityLevel != test.wantLevel {
+				t.Errorf("security level = %s, want %s", plan.SecurityLevel, test.wantLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pod annotations read by the CNI bandwidth plugin to shape traffic
+const (
+	ingressBandwidthAnnotation = "kubernetes.io/ingress-bandwidth"
+	egressBandwidthAnnotation  = "kubernetes.io/egress-bandwidth"
+)
+
+// Converts a decided bandwidth such as "200Mbps" to the quantity the
+// bandwidth plugin expects, e.g. "200M"
+func bandwidthQuantity(bandwidth string) string {
+	mbps, err := parseBandwidthMbps(bandwidth)
+	if err != nil {
+		return bandwidth
+	}
+	return fmt.Sprintf("%gM", mbps)
+}
+
+// Renders the pod template annotations that enforce the decided bandwidth
+func bandwidthAnnotations(network NetworkSpec) string {
+	quantity := bandwidthQuantity(network.Bandwidth)
+	return fmt.Sprintf("        %s: %q\n        %s: %q\n",
+		ingressBandwidthAnnotation, quantity, egressBandwidthAnnotation, quantity)
+}
+
+// Whether the spec restricts who may reach the app
+func (config *ConfigSpec) restrictsIngress() bool {
+	return len(config.AllowedNamespaces) > 0 || len(config.AllowedLabels) > 0
+}
+
+// Parses comma-separated "key=value" pod labels
+func parseLabels(value string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range splitList(value) {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("label %q is not key=value", pair)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return labels, validateLabels(labels)
+}
+
+// Label names and values: up to 63 alphanumerics, '-', '_' or '.', starting
+// and ending alphanumeric. Keys may have a DNS subdomain prefix and a slash.
+var (
+	labelNamePattern    = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	dnsSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// Checks label keys and values against the Kubernetes label syntax
+func validateLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		name := key
+		if prefix, rest, ok := strings.Cut(key, "/"); ok {
+			if !dnsSubdomainPattern.MatchString(prefix) || len(prefix) > 253 {
+				return fmt.Errorf("label key %q must have a DNS subdomain prefix", key)
+			}
+			name = rest
+		}
+		if !labelNamePattern.MatchString(name) || len(name) > 63 {
+			return fmt.Errorf("label key %q is not a valid label name", key)
+		}
+		if value := labels[key]; value != "" && (!labelNamePattern.MatchString(value) || len(value) > 63) {
+			return fmt.Errorf("label value %q of %s is not a valid label value", value, key)
+		}
+	}
+	return nil
+}
+
+// Checks that namespaces are DNS labels
+func validateNamespaces(namespaces []string) error {
+	for _, namespace := range namespaces {
+		if !dnsLabelPattern.MatchString(namespace) || len(namespace) > 63 {
+			return fmt.Errorf("namespace %q must be a DNS label", namespace)
+		}
+	}
+	return nil
+}
+
+// Formats pod labels as comma-separated "key=value" pairs, sorted by key
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Generates a NetworkPolicy admitting traffic only to the decided ports and
+// only from the allowed namespaces and pod labels. Both together select the
+// labelled pods within the allowed namespaces.
+func generateNetworkPolicy(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	var peer strings.Builder
+	prefix := "    - "
+	if len(config.AllowedNamespaces) > 0 {
+		peer.WriteString(prefix + "namespaceSelector:\n")
+		peer.WriteString("        matchExpressions:\n")
+		peer.WriteString("          - key: kubernetes.io/metadata.name\n")
+		peer.WriteString("            operator: In\n")
+		peer.WriteString("            values:\n")
+		for _, namespace := range config.AllowedNamespaces {
+			peer.WriteString(fmt.Sprintf("              - %s\n", namespace))
+		}
+		prefix = "      "
+	}
+	if len(config.AllowedLabels) > 0 {
+		peer.WriteString(prefix + "podSelector:\n")
+		peer.WriteString("        matchLabels:\n")
+		for _, pair := range strings.Split(formatLabels(config.AllowedLabels), ",") {
+			key, value, _ := strings.Cut(pair, "=")
+			peer.WriteString(fmt.Sprintf("          %s: %q\n", key, value))
+		}
+	}
+
+	var ports strings.Builder
+	for _, port := range timedResults["network"].NetworkSpec.Ports {
+		ports.WriteString(fmt.Sprintf("    - port: %s\n      protocol: %s\n", port.Name, port.Protocol))
+	}
+
+	return fmt.Sprintf(`
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: %s-ingress
+spec:
+  podSelector:
+    matchLabels:
+      app: %s
+  policyTypes:
+    - Ingress
+  ingress:
+  - from:
+%s    ports:
+%s`, config.AppName, config.AppName, peer.String(), ports.String())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr string // Part of the error, none if empty
+	}{
+		{name: "empty", value: "", want: map[string]string{}},
+		{name: "pairs are trimmed", value: " role = frontend , tier=web", want: map[string]string{"role": "frontend", "tier": "web"}},
+		{name: "empty value", value: "canary=", want: map[string]string{"canary": ""}},
+		{name: "prefixed key", value: "app.kubernetes.io/name=shop", want: map[string]string{"app.kubernetes.io/name": "shop"}},
+		{name: "not a pair", value: "frontend", wantErr: "not key=value"},
+		{name: "empty key", value: "=frontend", wantErr: "not key=value"},
+		{name: "key starts with a dash", value: "-role=frontend", wantErr: "not a valid label name"},
+		{name: "key too long", value: strings.Repeat("k", 64) + "=v", wantErr: "not a valid label name"},
+		{name: "upper-case prefix", value: "Example.com/role=frontend", wantErr: "DNS subdomain prefix"},
+		{name: "empty name after prefix", value: "example.com/=frontend", wantErr: "not a valid label name"},
+		{name: "value with a space", value: "team=pay ments", wantErr: "not a valid label value"},
+		{name: "value too long", value: "team=" + strings.Repeat("v", 64), wantErr: "not a valid label value"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseLabels(test.value)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("parseLabels(%q) error = %v, want %q", test.value, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLabels(%q): %v", test.value, err)
+			}
+			if !maps.Equal(got, test.want) {
+				t.Errorf("parseLabels(%q) = %v, want %v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := map[string]string{"tier": "web", "role": "frontend", "canary": ""}
+	if got, want := formatLabels(labels), "canary=,role=frontend,tier=web"; got != want {
+		t.Errorf("formatLabels = %q, want %q", got, want)
+	}
+}
+
+func TestValidateNamespaces(t *testing.T) {
+	tests := []struct {
+		namespaces []string
+		valid      bool
+	}{
+		{nil, true},
+		{[]string{"web", "payments-2"}, true},
+		{[]string{"web", "Payments"}, false},
+		{[]string{"team_a"}, false},
+		{[]string{"-web"}, false},
+		{[]string{strings.Repeat("n", 64)}, false},
+	}
+	for _, test := range tests {
+		if err := validateNamespaces(test.namespaces); (err == nil) != test.valid {
+			t.Errorf("validateNamespaces(%q) error = %v, want valid %v", test.namespaces, err, test.valid)
+		}
+	}
+}
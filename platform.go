@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// platformConfig holds the policies a platform team sets for every app of
+// the cluster, read from the config file in the state directory.
+type platformConfig struct {
+	Tiers           []importanceTier               `yaml:"tiers"`           // Replace the built-in importance levels when set
+	Availability    map[string]availabilityPolicy  `yaml:"availability"`    // By importance level
+	PriorityClasses map[string]priorityClassPolicy `yaml:"priorityClasses"` // By importance level
+
+	VerticalAutoscaling map[string]verticalAutoscalingPolicy `yaml:"verticalAutoscaling"` // By importance level
+
+	KubernetesVersion string `yaml:"kubernetesVersion"` // Cluster version manifests are validated for, e.g. "1.30"
+}
+
+// Policies used for importance levels the config file does not mention
+var defaultPlatformConfig = platformConfig{
+	Tiers: defaultTiers,
+	Availability: map[string]availabilityPolicy{
+		"high": {
+			MinReplicas:    3,
+			MaxUnavailable: "1",
+			ZoneSpread:     scheduleConstraintPreferred,
+			NodeSpread:     scheduleConstraintRequired,
+			AntiAffinity:   scheduleConstraintPreferred,
+		},
+	},
+	PriorityClasses: map[string]priorityClassPolicy{
+		"high":   {Name: "tiny-workloads-high", Value: 100000, PreemptionPolicy: "PreemptLowerPriority"},
+		"medium": {Name: "tiny-workloads-medium", Value: 10000, PreemptionPolicy: "PreemptLowerPriority"},
+		"low":    {Name: "tiny-workloads-low", Value: 1000, PreemptionPolicy: "Never"},
+	},
+	// The more important the app, the less the autoscaler may touch it on its
+	// own: high only recommends, low is resized and evicted freely
+	VerticalAutoscaling: map[string]verticalAutoscalingPolicy{
+		"high":   {UpdateMode: "Off", MinAllowedPercent: 50, MaxAllowedPercent: 200},
+		"medium": {UpdateMode: "Initial", MinAllowedPercent: 50, MaxAllowedPercent: 200},
+		"low":    {UpdateMode: "Auto", MinAllowedPercent: 25, MaxAllowedPercent: 200},
+	},
+}
+
+// Path of the platform config file
+func platformConfigPath() string {
+	return filepath.Join(stateDir, "config.yaml")
+}
+
+// Loads the platform config. Each importance level the file configures
+// replaces the default policy for that level, the others keep the defaults.
+func loadPlatformConfig() (platformConfig, error) {
+	config := platformConfig{
+		Tiers:           defaultPlatformConfig.Tiers,
+		Availability:    maps.Clone(defaultPlatformConfig.Availability),
+		PriorityClasses: maps.Clone(defaultPlatformConfig.PriorityClasses),
+
+		VerticalAutoscaling: maps.Clone(defaultPlatformConfig.VerticalAutoscaling),
+	}
+
+	data, err := os.ReadFile(platformConfigPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("error reading platform config: %v", err)
+	}
+
+	var file platformConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return config, fmt.Errorf("error parsing %s: %v", platformConfigPath(), err)
+	}
+	for level, policy := range file.Availability {
+		if err := policy.validate(); err != nil {
+			return config, fmt.Errorf("%s: availability of %s: %v", platformConfigPath(), level, err)
+		}
+		config.Availability[level] = policy
+	}
+	for level, policy := range file.PriorityClasses {
+		if err := policy.validate(); err != nil {
+			return config, fmt.Errorf("%s: priority class of %s: %v", platformConfigPath(), level, err)
+		}
+		config.PriorityClasses[level] = policy
+	}
+	for level, policy := range file.VerticalAutoscaling {
+		if err := policy.validate(); err != nil {
+			return config, fmt.Errorf("%s: vertical autoscaling of %s: %v", platformConfigPath(), level, err)
+		}
+		config.VerticalAutoscaling[level] = policy
+	}
+	config.KubernetesVersion = file.KubernetesVersion
+
+	// Custom tiers replace the built-in ones, their scheduling settings
+	// override the ones given by level
+	if len(file.Tiers) > 0 {
+		config.Tiers = file.Tiers
+	}
+	seen := make(map[string]bool)
+	for _, tier := range file.Tiers {
+		if err := tier.validate(); err != nil {
+			return config, fmt.Errorf("%s: tier %s: %v", platformConfigPath(), tier.Name, err)
+		}
+		if seen[tier.Name] {
+			return config, fmt.Errorf("%s: tier %s is defined twice", platformConfigPath(), tier.Name)
+		}
+		seen[tier.Name] = true
+
+		if tier.Availability != nil {
+			if err := tier.Availability.validate(); err != nil {
+				return config, fmt.Errorf("%s: availability of %s: %v", platformConfigPath(), tier.Name, err)
+			}
+			config.Availability[tier.Name] = *tier.Availability
+		}
+		if tier.MinReplicas > 0 {
+			policy := config.Availability[tier.Name]
+			policy.MinReplicas = tier.MinReplicas
+			config.Availability[tier.Name] = policy
+		}
+		if tier.PriorityClass != nil {
+			if err := tier.PriorityClass.validate(); err != nil {
+				return config, fmt.Errorf("%s: priority class of %s: %v", platformConfigPath(), tier.Name, err)
+			}
+			config.PriorityClasses[tier.Name] = *tier.PriorityClass
+		}
+		if tier.VerticalAutoscaling != nil {
+			if err := tier.VerticalAutoscaling.validate(); err != nil {
+				return config, fmt.Errorf("%s: vertical autoscaling of %s: %v", platformConfigPath(), tier.Name, err)
+			}
+			config.VerticalAutoscaling[tier.Name] = *tier.VerticalAutoscaling
+		}
+	}
+	return config, nil
+}
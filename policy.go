@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of policy rules, most severe first. Error violations fail
+// headless runs.
+var policySeverities = []string{"error", "warning", "info"}
+
+// policyFile is one file of organisational rules in the policy directory,
+// for example:
+//
+//	rules:
+//	  - name: cpu-cap
+//	    check: maxPodResource
+//	    resource: cpu
+//	    max: "4"
+//	  - name: team-label
+//	    severity: warning
+//	    check: requiredLabels
+//	    kinds: [Deployment]
+//	    labels: [team]
+type policyFile struct {
+	Rules []policyRule `yaml:"rules"`
+}
+
+// policyRule applies one check with its parameters to the generated objects.
+type policyRule struct {
+	Name     string   `yaml:"name"`
+	Severity string   `yaml:"severity"` // "error", "warning" or "info", error if unset
+	Check    string   `yaml:"check"`    // One of policyChecks
+	Kinds    []string `yaml:"kinds"`    // Kinds the rule applies to, all if unset
+
+	// Parameters, used by the checks named
+	Resource string   `yaml:"resource"` // maxPodResource: "cpu" or "memory"
+	Max      string   `yaml:"max"`      // maxPodResource: quantity the pod's containers may add up to
+	Labels   []string `yaml:"labels"`   // requiredLabels
+	Probes   []string `yaml:"probes"`   // requiredProbes: "startup", "liveness" or "readiness"
+	Tags     []string `yaml:"tags"`     // bannedImageTags, an image without tag counts as "latest"
+	Path     string   `yaml:"path"`     // requiredField: dotted path, [*] for every array entry
+	Equals   string   `yaml:"equals"`   // requiredField: value the field must have, any if unset
+}
+
+// policyCheck is a kind of rule: how its parameters are checked and how it
+// finds violations in an object.
+type policyCheck struct {
+	validate func(rule policyRule) error
+	evaluate func(rule policyRule, object *yaml.Node) []string
+}
+
+// Checks rules can use, by name
+var policyChecks = map[string]policyCheck{
+	"maxPodResource":  {validateMaxPodResource, evaluateMaxPodResource},
+	"requiredLabels":  {requireParameter("labels", func(rule policyRule) bool { return len(rule.Labels) > 0 }), evaluateRequiredLabels},
+	"requiredProbes":  {validateRequiredProbes, evaluateRequiredProbes},
+	"bannedImageTags": {requireParameter("tags", func(rule policyRule) bool { return len(rule.Tags) > 0 }), evaluateBannedImageTags},
+	"requiredField":   {requireParameter("path", func(rule policyRule) bool { return rule.Path != "" }), evaluateRequiredField},
+}
+
+// policyViolation is an object breaking a rule.
+type policyViolation struct {
+	Rule     string
+	Severity string
+	File     string
+	Kind     string
+	Name     string
+	Message  string
+}
+
+// Rules used when the policy directory holds no files. A mutable tag can
+// point at a different image on every pull, so it blocks the write.
+var defaultPolicyRules = []policyRule{
+	{Name: "no-latest-tag", Severity: "error", Check: "bannedImageTags", Tags: []string{"latest"}},
+}
+
+// Directory holding the policy files
+func policyDir() string {
+	return filepath.Join(stateDir, "policies")
+}
+
+// Loads the rules of every policy file, falling back to the default rules
+// when there are none
+func loadPolicies() ([]policyRule, error) {
+	paths, err := filepath.Glob(filepath.Join(policyDir(), "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return defaultPolicyRules, nil
+	}
+
+	var rules []policyRule
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading policy file: %v", err)
+		}
+
+		var file policyFile
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+		for _, rule := range file.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("%s: rule %s: %v", path, rule.Name, err)
+			}
+			if rule.Severity == "" {
+				rule.Severity = "error"
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// Checks the rule's name, severity, check and the check's parameters
+func (rule policyRule) validate() error {
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rule.Severity != "" && !slices.Contains(policySeverities, rule.Severity) {
+		return fmt.Errorf("severity must be one of %v, got %q", policySeverities, rule.Severity)
+	}
+	check, ok := policyChecks[rule.Check]
+	if !ok {
+		return fmt.Errorf("unknown check %q", rule.Check)
+	}
+	return check.validate(rule)
+}
+
+// Validation of checks that only need one parameter set
+func requireParameter(name string, isSet func(rule policyRule) bool) func(rule policyRule) error {
+	return func(rule policyRule) error {
+		if !isSet(rule) {
+			return fmt.Errorf("%s needs %s", rule.Check, name)
+		}
+		return nil
+	}
+}
+
+// Evaluates the rules against every object of the generated documents
+func evaluatePolicies(rules []policyRule, docs []manifestDocument) ([]policyViolation, error) {
+	var violations []policyViolation
+	for _, doc := range docs {
+		objects, err := decodeObjects(doc.File, doc.Content)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			violations = append(violations, evaluateObject(rules, doc.File, object)...)
+		}
+	}
+
+	// Most severe first, in document order within a severity and in rule
+	// order within an object
+	slices.SortStableFunc(violations, func(a, b policyViolation) int {
+		return slices.Index(policySeverities, a.Severity) - slices.Index(policySeverities, b.Severity)
+	})
+	return violations, nil
+}
+
+// Evaluates the rules that apply to one object
+func evaluateObject(rules []policyRule, file string, object *yaml.Node) []policyViolation {
+	kind := scalarValue(mappingValue(object, "kind"))
+	name := scalarValue(mappingValue(mappingValue(object, "metadata"), "name"))
+
+	var violations []policyViolation
+	for _, rule := range rules {
+		if len(rule.Kinds) > 0 && !slices.Contains(rule.Kinds, kind) {
+			continue
+		}
+		for _, message := range policyChecks[rule.Check].evaluate(rule, object) {
+			violations = append(violations, policyViolation{
+				Rule:     rule.Name,
+				Severity: rule.Severity,
+				File:     file,
+				Kind:     kind,
+				Name:     name,
+				Message:  message,
+			})
+		}
+	}
+	return violations
+}
+
+// Number of violations with error severity
+func policyErrors(violations []policyViolation) int {
+	count := 0
+	for _, violation := range violations {
+		if violation.Severity == "error" {
+			count++
+		}
+	}
+	return count
+}
+
+// Containers in the given lists of an object's pod template, none for
+// objects without pods
+func podContainers(object *yaml.Node, fields ...string) []*yaml.Node {
+	var containers []*yaml.Node
+	for _, field := range fields {
+		if list := mappingValue(podSpec(object), field); list != nil && list.Kind == yaml.SequenceNode {
+			containers = append(containers, list.Content...)
+		}
+	}
+	return containers
+}
+
+func validateMaxPodResource(rule policyRule) error {
+	switch rule.Resource {
+	case "cpu":
+		_, err := parseCPU(rule.Max)
+		return err
+	case "memory":
+		_, err := parseMemoryMi(rule.Max)
+		return err
+	}
+	return fmt.Errorf("maxPodResource needs resource cpu or memory, got %q", rule.Resource)
+}
+
+// Sums the containers' limits, or requests where a container has no limit,
+// and compares them to the maximum. Init containers run one at a time before
+// the others, so only the largest counts if it exceeds the sum.
+func evaluateMaxPodResource(rule policyRule, object *yaml.Node) []string {
+	parse := parseMemoryMi
+	if rule.Resource == "cpu" {
+		parse = parseCPU
+	}
+	limit, _ := parse(rule.Max)
+	quantity := func(container *yaml.Node) float64 {
+		resources := mappingValue(container, "resources")
+		quantity := scalarValue(mappingValue(mappingValue(resources, "limits"), rule.Resource))
+		if quantity == "" {
+			quantity = scalarValue(mappingValue(mappingValue(resources, "requests"), rule.Resource))
+		}
+		value, _ := parse(quantity)
+		return value
+	}
+
+	total := 0.0
+	for _, container := range podContainers(object, "containers") {
+		total += quantity(container)
+	}
+	for _, container := range podContainers(object, "initContainers") {
+		total = math.Max(total, quantity(container))
+	}
+	if total <= limit {
+		return nil
+	}
+	if rule.Resource == "cpu" {
+		return []string{fmt.Sprintf("pod uses %.2f CPU cores, at most %s allowed", total, rule.Max)}
+	}
+	return []string{fmt.Sprintf("pod uses %.0fMi of memory, at most %s allowed", total, rule.Max)}
+}
+
+// Requires the labels on the object and on its pod template, if it has one
+func evaluateRequiredLabels(rule policyRule, object *yaml.Node) []string {
+	var messages []string
+	check := func(metadata *yaml.Node, where string) {
+		labels := mappingValue(metadata, "labels")
+		for _, label := range rule.Labels {
+			if mappingValue(labels, label) == nil {
+				messages = append(messages, fmt.Sprintf("%s has no %q label", where, label))
+			}
+		}
+	}
+	check(mappingValue(object, "metadata"), "object")
+	if podSpec(object) != nil {
+		check(templateMetadata(object), "pod template")
+	}
+	return messages
+}
+
+// Probe kinds and their container fields
+var probeFields = map[string]string{
+	"startup":   "startupProbe",
+	"liveness":  "livenessProbe",
+	"readiness": "readinessProbe",
+}
+
+func validateRequiredProbes(rule policyRule) error {
+	if len(rule.Probes) == 0 {
+		return fmt.Errorf("requiredProbes needs probes")
+	}
+	for _, probe := range rule.Probes {
+		if _, ok := probeFields[probe]; !ok {
+			return fmt.Errorf("unknown probe %q, use startup, liveness or readiness", probe)
+		}
+	}
+	return nil
+}
+
+// Requires the probes on every regular container
+func evaluateRequiredProbes(rule policyRule, object *yaml.Node) []string {
+	var messages []string
+	for _, container := range podContainers(object, "containers") {
+		for _, probe := range rule.Probes {
+			if mappingValue(container, probeFields[probe]) == nil {
+				messages = append(messages, fmt.Sprintf("container %s has no %s probe", scalarValue(mappingValue(container, "name")), probe))
+			}
+		}
+	}
+	return messages
+}
+
+// Flags images with a banned tag. Images pinned by digest pass whatever
+// their tag.
+func evaluateBannedImageTags(rule policyRule, object *yaml.Node) []string {
+	var messages []string
+	for _, container := range podContainers(object, "initContainers", "containers") {
+		image := scalarValue(mappingValue(container, "image"))
+		if image == "" || strings.Contains(image, "@") {
+			continue
+		}
+		tag := "latest"
+		if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+			tag = image[i+1:]
+		}
+		if slices.Contains(rule.Tags, tag) {
+			messages = append(messages, fmt.Sprintf("container %s uses the banned tag %q (%s)", scalarValue(mappingValue(container, "name")), tag, image))
+		}
+	}
+	return messages
+}
+
+// Requires the field at the path, with the given value if one is set
+func evaluateRequiredField(rule policyRule, object *yaml.Node) []string {
+	var messages []string
+	for _, match := range fieldsAtPath(object, rule.Path, "") {
+		switch {
+		case match.node == nil:
+			messages = append(messages, fmt.Sprintf("%s is not set", match.path))
+		case rule.Equals != "" && scalarValue(match.node) != rule.Equals:
+			messages = append(messages, fmt.Sprintf("%s is %q, must be %q", match.path, scalarValue(match.node), rule.Equals))
+		}
+	}
+	return messages
+}
+
+// fieldMatch is a field a path leads to, nil if it is missing.
+type fieldMatch struct {
+	path string
+	node *yaml.Node
+}
+
+// Follows a dotted path, fanning out at each "[*]" into every array entry
+func fieldsAtPath(node *yaml.Node, path, prefix string) []fieldMatch {
+	if path == "" {
+		return []fieldMatch{{prefix, node}}
+	}
+	field, rest, _ := strings.Cut(path, ".")
+	name, each := strings.CutSuffix(field, "[*]")
+	fieldPath := joinFieldPath(prefix, name)
+
+	value := mappingValue(node, name)
+	if value == nil {
+		return []fieldMatch{{fieldPath, nil}}
+	}
+	if !each {
+		return fieldsAtPath(value, rest, fieldPath)
+	}
+	var matches []fieldMatch
+	for i, entry := range value.Content {
+		matches = append(matches, fieldsAtPath(entry, rest, fmt.Sprintf("%s[%d]", fieldPath, i))...)
+	}
+	return matches
+}
+
+// Describes the policy violations as Markdown
+func policyMarkdown(rules []policyRule, violations []policyViolation) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n## Policy Checks (%d rules)\n\n", len(rules)))
+	if len(violations) == 0 {
+		sb.WriteString("- Every object follows the policies\n")
+		return sb.String()
+	}
+	sb.WriteString("| Severity | Rule | Object | Violation |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, violation := range violations {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s %s | %s |\n",
+			violation.Severity, violation.Rule, violation.Kind, violation.Name, violation.Message))
+	}
+	return sb.String()
+}
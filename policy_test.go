@@ -0,0 +1,212 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// Deployment the policy checks are evaluated against
+const policyTestDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shop-deployment
+  labels:
+    app: shop
+spec:
+  template:
+    metadata:
+      labels:
+        app: shop
+        team: payments
+    spec:
+      initContainers:
+        - name: migrate
+          image: ghcr.io/acme/migrate:1.2
+          resources:
+            limits:
+              cpu: "3"
+              memory: 512Mi
+      containers:
+        - name: shop
+          image: ghcr.io/acme/shop
+          resources:
+            limits:
+              cpu: "1.5"
+              memory: 1Gi
+          readinessProbe:
+            tcpSocket:
+              port: http
+          securityContext:
+            runAsNonRoot: true
+        - name: proxy
+          image: registry:5000/envoy@sha256:abc
+          resources:
+            requests:
+              cpu: 250m
+              memory: 128Mi
+          readinessProbe:
+            tcpSocket:
+              port: admin
+`
+
+func TestPolicyChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		rule policyRule
+		want []string
+	}{
+		{
+			name: "cpu under the maximum",
+			rule: policyRule{Check: "maxPodResource", Resource: "cpu", Max: "4"},
+		},
+		{
+			name: "cpu counts the largest init container over the sum",
+			rule: policyRule{Check: "maxPodResource", Resource: "cpu", Max: "2"},
+			want: []string{"pod uses 3.00 CPU cores, at most 2 allowed"},
+		},
+		{
+			name: "memory falls back to requests",
+			rule: policyRule{Check: "maxPodResource", Resource: "memory", Max: "1Gi"},
+			want: []string{"pod uses 1152Mi of memory, at most 1Gi allowed"},
+		},
+		{
+			name: "labels on the object and the pod template",
+			rule: policyRule{Check: "requiredLabels", Labels: []string{"app", "team"}},
+			want: []string{`object has no "team" label`},
+		},
+		{
+			name: "probes on every regular container",
+			rule: policyRule{Check: "requiredProbes", Probes: []string{"readiness", "liveness"}},
+			want: []string{"container shop has no liveness probe", "container proxy has no liveness probe"},
+		},
+		{
+			name: "image without tag counts as latest, digests pass",
+			rule: policyRule{Check: "bannedImageTags", Tags: []string{"latest", "5000"}},
+			want: []string{`container shop uses the banned tag "latest" (ghcr.io/acme/shop)`},
+		},
+		{
+			name: "field on every container",
+			rule: policyRule{Check: "requiredField", Path: "spec.template.spec.containers[*].securityContext.runAsNonRoot", Equals: "true"},
+			want: []string{"spec.template.spec.containers[1].securityContext is not set"},
+		},
+		{
+			name: "field with the wrong value",
+			rule: policyRule{Check: "requiredField", Path: "metadata.labels.app", Equals: "store"},
+			want: []string{`metadata.labels.app is "shop", must be "store"`},
+		},
+		{
+			name: "rule limited to other kinds",
+			rule: policyRule{Check: "requiredLabels", Labels: []string{"owner"}, Kinds: []string{"Service"}},
+		},
+	}
+	docs := []manifestDocument{{Kind: "Deployment", Name: "shop-deployment", File: "shop-deployment.yaml", Content: policyTestDeployment}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.rule.Name, test.rule.Severity = "rule", "error"
+			if err := test.rule.validate(); err != nil {
+				t.Fatalf("rule does not validate: %v", err)
+			}
+			violations, err := evaluatePolicies([]policyRule{test.rule}, docs)
+			if err != nil {
+				t.Fatalf("evaluatePolicies: %v", err)
+			}
+			var got []string
+			for _, violation := range violations {
+				got = append(got, violation.Message)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("violations = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPolicyRuleValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  policyRule
+		valid bool
+	}{
+		{"missing name", policyRule{Check: "requiredLabels", Labels: []string{"team"}}, false},
+		{"unknown severity", policyRule{Name: "r", Severity: "fatal", Check: "requiredLabels", Labels: []string{"team"}}, false},
+		{"unknown check", policyRule{Name: "r", Check: "maxReplicas"}, false},
+		{"maxPodResource without resource", policyRule{Name: "r", Check: "maxPodResource", Max: "1"}, false},
+		{"maxPodResource with a bad quantity", policyRule{Name: "r", Check: "maxPodResource", Resource: "memory", Max: "lots"}, false},
+		{"requiredLabels without labels", policyRule{Name: "r", Check: "requiredLabels"}, false},
+		{"requiredProbes with an unknown probe", policyRule{Name: "r", Check: "requiredProbes", Probes: []string{"health"}}, false},
+		{"bannedImageTags without tags", policyRule{Name: "r", Check: "bannedImageTags"}, false},
+		{"requiredField without path", policyRule{Name: "r", Check: "requiredField", Equals: "true"}, false},
+		{"warning", policyRule{Name: "r", Severity: "warning", Check: "requiredProbes", Probes: []string{"startup"}}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.rule.validate(); (err == nil) != test.valid {
+				t.Errorf("validate() error = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
+
+func TestLoadPolicies(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	rules, err := loadPolicies()
+	if err != nil {
+		t.Fatalf("loadPolicies: %v", err)
+	}
+	if !slices.EqualFunc(rules, defaultPolicyRules, func(a, b policyRule) bool { return a.Name == b.Name }) {
+		t.Errorf("loadPolicies without files = %+v, want the default rules", rules)
+	}
+	docs := []manifestDocument{{Kind: "Deployment", Name: "shop-deployment", File: "shop-deployment.yaml",
+		Content: "kind: Deployment\nmetadata:\n  name: shop-deployment\nspec:\n  template:\n    spec:\n      containers:\n        - name: shop\n          image: your-app-image:latest\n"}}
+	violations, err := evaluatePolicies(rules, docs)
+	if err != nil {
+		t.Fatalf("evaluatePolicies: %v", err)
+	}
+	if policyErrors(violations) != 1 {
+		t.Errorf("default rules found %+v, want one error for the latest tag", violations)
+	}
+
+	// Policy files replace the default rules
+	if err := os.MkdirAll(policyDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	policy := "rules:\n  - name: team-label\n    check: requiredLabels\n    labels: [team]\n"
+	if err := os.WriteFile(filepath.Join(policyDir(), "labels.yaml"), []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err = loadPolicies()
+	if err != nil {
+		t.Fatalf("loadPolicies: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Name != "team-label" || rules[0].Severity != "error" {
+		t.Errorf("loadPolicies = %+v, want team-label with error severity", rules)
+	}
+}
+
+func TestPolicyViolationOrder(t *testing.T) {
+	rules := []policyRule{
+		{Name: "team", Severity: "warning", Check: "requiredLabels", Labels: []string{"team"}},
+		{Name: "owner", Severity: "error", Check: "requiredLabels", Labels: []string{"owner"}},
+		{Name: "tier", Severity: "warning", Check: "requiredLabels", Labels: []string{"tier"}},
+	}
+	docs := []manifestDocument{
+		{Kind: "Service", Name: "a", File: "a.yaml", Content: "kind: Service\nmetadata:\n  name: a\n"},
+		{Kind: "Service", Name: "b", File: "b.yaml", Content: "kind: Service\nmetadata:\n  name: b\n"},
+	}
+	violations, err := evaluatePolicies(rules, docs)
+	if err != nil {
+		t.Fatalf("evaluatePolicies: %v", err)
+	}
+	var got []string
+	for _, violation := range violations {
+		got = append(got, violation.Name+"/"+violation.Rule)
+	}
+	want := []string{"a/owner", "b/owner", "a/team", "a/tier", "b/team", "b/tier"}
+	if !slices.Equal(got, want) {
+		t.Errorf("violations in order %q, want %q", got, want)
+	}
+}
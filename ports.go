@@ -0,0 +1,162 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ServicePort is a port the app serves, as declared in the spec or decided.
+type ServicePort struct {
+	Name        string `yaml:"name"`
+	Port        int    `yaml:"port"`
+	Protocol    string `yaml:"protocol,omitempty"`    // TCP, UDP or SCTP, TCP if unset
+	AppProtocol string `yaml:"appProtocol,omitempty"` // e.g. http, grpc, kubernetes.io/h2c
+}
+
+// Transport protocols Kubernetes accepts for ports
+var portProtocols = []string{"TCP", "UDP", "SCTP"}
+
+// Port added when the spec asks for metrics to be exposed, the Prometheus default
+var metricsPort = ServicePort{Name: "metrics", Port: 9090, Protocol: "TCP", AppProtocol: "http"}
+
+// Port names must be IANA service names so Services and probes can refer to them
+var portNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Port serving HTTPS for tiers that ask for it
+var httpsPort = ServicePort{Name: "https", Port: 443, Protocol: "TCP", AppProtocol: "https"}
+
+// Ports of apps that declare none: plain HTTP, plus HTTPS if the tier asks for it
+func defaultPorts(https bool) []ServicePort {
+	ports := []ServicePort{{Name: "http", Port: 8080, Protocol: "TCP", AppProtocol: "http"}}
+	if https {
+		ports = append(ports, httpsPort)
+	}
+	return ports
+}
+
+// Decides the ports of the app: the declared ones or the defaults, HTTPS if
+// the tier asks for it and the metrics port when requested. Errors on ports
+// that Kubernetes would reject or that clash with each other.
+func (config *ConfigSpec) decidePorts() ([]ServicePort, error) {
+	tier, err := config.tier()
+	if err != nil {
+		return nil, err
+	}
+
+	ports := defaultPorts(tier.HTTPS)
+	if len(config.Ports) > 0 {
+		ports = nil
+		for _, port := range config.Ports {
+			if port.Protocol == "" {
+				port.Protocol = "TCP"
+			}
+			ports = append(ports, port)
+		}
+		if tier.HTTPS && !slices.ContainsFunc(ports, isHTTPSPort) {
+			ports = append(ports, httpsPort)
+		}
+	}
+	if config.ExposeMetrics && !slices.ContainsFunc(ports, func(port ServicePort) bool { return port.Name == metricsPort.Name }) {
+		ports = append(ports, metricsPort)
+	}
+
+	if err := validatePorts(ports); err != nil {
+		return nil, err
+	}
+	return ports, nil
+}
+
+// Checks port names, numbers and protocols, and that no two ports share a
+// name or a number and protocol
+func validatePorts(ports []ServicePort) error {
+	names := make(map[string]bool)
+	numbers := make(map[string]string)
+	for _, port := range ports {
+		if len(port.Name) > 15 || !portNamePattern.MatchString(port.Name) || !strings.ContainsAny(port.Name, "abcdefghijklmnopqrstuvwxyz") {
+			return fmt.Errorf("port name %q must be at most 15 lowercase letters, digits and dashes with at least one letter", port.Name)
+		}
+		if port.Port < 1 || port.Port > 65535 {
+			return fmt.Errorf("port %s: number %d is outside 1-65535", port.Name, port.Port)
+		}
+		if !slices.Contains(portProtocols, port.Protocol) {
+			return fmt.Errorf("port %s: protocol must be one of %v, got %q", port.Name, portProtocols, port.Protocol)
+		}
+		if names[port.Name] {
+			return fmt.Errorf("port name %s is declared twice", port.Name)
+		}
+		names[port.Name] = true
+
+		key := fmt.Sprintf("%d/%s", port.Port, port.Protocol)
+		if other, ok := numbers[key]; ok {
+			return fmt.Errorf("ports %s and %s both use %s", other, port.Name, key)
+		}
+		numbers[key] = port.Name
+	}
+	return nil
+}
+
+func isHTTPSPort(port ServicePort) bool {
+	return port.Port == 443 || port.Name == "https"
+}
+
+// Port numbers of the ports, in order
+func portNumbers(ports []ServicePort) []int {
+	numbers := make([]int, len(ports))
+	for i, port := range ports {
+		numbers[i] = port.Port
+	}
+	return numbers
+}
+
+// Parses ports written as name:number[/protocol[/appProtocol]], comma-separated
+func parsePorts(value string) ([]ServicePort, error) {
+	var ports []ServicePort
+	for _, entry := range splitList(value) {
+		name, rest, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("port %q is not name:number", entry)
+		}
+		fields := strings.SplitN(rest, "/", 3)
+		number, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("port %q has no valid number", entry)
+		}
+		port := ServicePort{Name: name, Port: number}
+		if len(fields) > 1 {
+			port.Protocol = strings.ToUpper(fields[1])
+		}
+		if len(fields) > 2 {
+			port.AppProtocol = fields[2]
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// Formats ports in the form parsePorts reads
+func formatPorts(ports []ServicePort) string {
+	entries := make([]string, len(ports))
+	for i, port := range ports {
+		entries[i] = fmt.Sprintf("%s:%d", port.Name, port.Port)
+		switch {
+		case port.AppProtocol != "":
+			entries[i] += "/" + cmp.Or(port.Protocol, "TCP") + "/" + port.AppProtocol
+		case port.Protocol != "":
+			entries[i] += "/" + port.Protocol
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// Describes a port for summaries, e.g. "grpc:9000/TCP (grpc)"
+func (port ServicePort) String() string {
+	description := fmt.Sprintf("%s:%d/%s", port.Name, port.Port, port.Protocol)
+	if port.AppProtocol != "" {
+		description += " (" + port.AppProtocol + ")"
+	}
+	return description
+}
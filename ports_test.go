@@ -0,0 +1,78 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []ServicePort
+		wantErr bool
+	}{
+		{name: "empty", value: ""},
+		{name: "name and number", value: "http:8080", want: []ServicePort{{Name: "http", Port: 8080}}},
+		{
+			name:  "protocol is upper-cased",
+			value: "gossip:7946/udp",
+			want:  []ServicePort{{Name: "gossip", Port: 7946, Protocol: "UDP"}},
+		},
+		{
+			name:  "app protocol may contain a slash",
+			value: "grpc:9000/TCP/grpc, web:8080/TCP/kubernetes.io/h2c",
+			want: []ServicePort{
+				{Name: "grpc", Port: 9000, Protocol: "TCP", AppProtocol: "grpc"},
+				{Name: "web", Port: 8080, Protocol: "TCP", AppProtocol: "kubernetes.io/h2c"},
+			},
+		},
+		{name: "missing number", value: "http", wantErr: true},
+		{name: "number not numeric", value: "http:web", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parsePorts(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parsePorts(%q) error = %v, want error %v", test.value, err, test.wantErr)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("parsePorts(%q) = %+v, want %+v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []ServicePort
+		want  string
+	}{
+		{name: "none", want: ""},
+		{name: "name and number", ports: []ServicePort{{Name: "http", Port: 8080}}, want: "http:8080"},
+		{name: "protocol", ports: []ServicePort{{Name: "dns", Port: 53, Protocol: "UDP"}}, want: "dns:53/UDP"},
+		{
+			name:  "app protocol without protocol defaults to TCP",
+			ports: []ServicePort{{Name: "grpc", Port: 9000, AppProtocol: "grpc"}, {Name: "metrics", Port: 9090}},
+			want:  "grpc:9000/TCP/grpc,metrics:9090",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := formatPorts(test.ports)
+			if got != test.want {
+				t.Errorf("formatPorts = %q, want %q", got, test.want)
+			}
+
+			// What formatPorts writes, parsePorts reads back
+			parsed, err := parsePorts(got)
+			if err != nil {
+				t.Fatalf("parsePorts(%q): %v", got, err)
+			}
+			if formatPorts(parsed) != got {
+				t.Errorf("round trip of %q gave %q", got, formatPorts(parsed))
+			}
+		})
+	}
+}
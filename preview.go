@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/quick"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PreviewMsg carries a freshly generated manifest preview back to the model.
+// Seq is compared against the model's latest request so that slow, stale
+// previews never overwrite newer ones.
+type PreviewMsg struct {
+	Seq    int
+	Result map[string]TimedResult
+	Docs   []manifestDocument
+	Err    error
+}
+
+// previewDebounceMsg fires once typing has paused. A preview is only
+// generated when Seq is still the latest request, so a burst of keystrokes
+// runs the deciders once.
+type previewDebounceMsg struct {
+	Seq int
+}
+
+// Preview pane layout
+const (
+	formColumnWidth      = labelWidth + inputWidth + 6 // Labels, inputs and their borders
+	previewMinSplitWidth = 110                         // Below this the preview is stacked under the form
+	previewStackedHeight = 12
+	previewDefaultWidth  = 80
+	previewDefaultHeight = 24
+)
+
+// Pause after the last change before the deciders run for the preview
+const previewDebounce = 200 * time.Millisecond
+
+var (
+	previewBorderStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("#666666")).Padding(0, 1)
+	previewTabStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#666666")).PaddingRight(2)
+	previewActiveTabStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#00FF00")).Bold(true).PaddingRight(2)
+	previewErrorStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000"))
+)
+
+// Command that runs the deciders for the current wizard values and renders
+// the resulting manifests for the preview pane
+func previewCmd(seq int, config ConfigSpec) tea.Cmd {
+	return func() tea.Msg {
+		timedResults, err := collectTimedResourceSpecs(&config)
+		if err != nil {
+			return PreviewMsg{Seq: seq, Err: err}
+		}
+		return PreviewMsg{
+			Seq:    seq,
+			Result: timedResults,
+			Docs:   generateManifests(&config, timedResults),
+		}
+	}
+}
+
+// Builds a ConfigSpec from whatever has been entered so far. Empty or invalid
+// values are left at zero so the preview renders before the wizard is complete.
+func (m model) previewConfig() ConfigSpec {
+	var config ConfigSpec
+	m.applyValidInputs(&config)
+	if config.AppName == "" {
+		config.AppName = "my-app"
+	}
+	if selectedItem, ok := m.list.SelectedItem().(item); ok {
+		config.ImportanceLevel = selectedItem.name
+	}
+	return config
+}
+
+// Identifies the wizard values a preview was generated from
+func (m model) previewInputKey() string {
+	var b strings.Builder
+	for _, input := range m.inputs {
+		b.WriteString(input.Value())
+		b.WriteByte(0)
+	}
+	b.WriteString(strconv.Itoa(m.list.Index()))
+	return b.String()
+}
+
+// Schedules a new preview when the wizard values changed since the last one.
+// The deciders run once the values have not changed for previewDebounce.
+func (m *model) refreshPreview() tea.Cmd {
+	key := m.previewInputKey()
+	if key == m.previewInputs {
+		return nil
+	}
+	m.previewInputs = key
+	m.previewSeq++
+	seq := m.previewSeq
+	return tea.Tick(previewDebounce, func(time.Time) tea.Msg {
+		return previewDebounceMsg{Seq: seq}
+	})
+}
+
+// Generates the scheduled preview unless a newer change superseded it
+func (m model) debouncedPreview(msg previewDebounceMsg) tea.Cmd {
+	if msg.Seq != m.previewSeq {
+		return nil
+	}
+	return previewCmd(m.previewSeq, m.previewConfig())
+}
+
+// Applies a preview result, keeping the selected document and scroll position
+func (m *model) applyPreview(msg PreviewMsg) {
+	if msg.Seq != m.previewSeq {
+		return // Stale result, a newer preview is on its way
+	}
+
+	m.previewErr = msg.Err
+	if msg.Err != nil {
+		// The pane shows the error, a summary of older values would mislead
+		m.previewSummary = ""
+		return
+	}
+
+	m.previewDocs = msg.Docs
+	if m.previewIndex >= len(m.previewDocs) {
+		m.previewIndex = 0
+	}
+
+	summary, err := renderMarkdown(summaryMarkdown(msg.Result), formColumnWidth-4) // Leave room for Glamour margins
+	if err != nil {
+		summary = summaryMarkdown(msg.Result)
+	}
+	m.previewSummary = summary
+
+	m.showPreviewDocument()
+}
+
+// Moves the preview to the next generated document
+func (m *model) nextPreviewDocument() {
+	if len(m.previewDocs) == 0 {
+		return
+	}
+	m.previewIndex = (m.previewIndex + 1) % len(m.previewDocs)
+	m.showPreviewDocument()
+	m.preview.GotoTop()
+}
+
+func (m *model) showPreviewDocument() {
+	if len(m.previewDocs) == 0 {
+		m.preview.SetContent("")
+		return
+	}
+	m.preview.SetContent(highlight(m.previewDocs[m.previewIndex].Content, "yaml"))
+}
+
+// Sizes the preview viewport for the current terminal dimensions
+func (m *model) resizePreview() {
+	width, height := m.width, m.height
+	if width == 0 {
+		width = previewDefaultWidth
+	}
+	if height == 0 {
+		height = previewDefaultHeight
+	}
+
+	if width >= previewMinSplitWidth {
+		m.preview.Width = width - formColumnWidth - 4 // Border and padding
+		m.preview.Height = max(5, height-6)           // Tabs, border and help line
+	} else {
+		m.preview.Width = max(20, width-4)
+		m.preview.Height = previewStackedHeight
+	}
+}
+
+// Renders the preview pane: document tabs above the scrollable manifest
+func (m model) previewView() string {
+	var tabs []string
+	for i, doc := range m.previewDocs {
+		style := previewTabStyle
+		if i == m.previewIndex {
+			style = previewActiveTabStyle
+		}
+		tabs = append(tabs, style.Render(doc.Kind))
+	}
+
+	body := m.preview.View()
+	if m.previewErr != nil {
+		body = previewErrorStyle.Width(m.preview.Width).Render(fmt.Sprintf("Preview unavailable: %v", m.previewErr))
+	} else if len(m.previewDocs) == 0 {
+		body = "Generating preview..."
+	}
+
+	footer := previewTabStyle.Render(fmt.Sprintf("%3.f%%", m.preview.ScrollPercent()*100))
+	return previewBorderStyle.Render(lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, tabs...),
+		body,
+		footer,
+	))
+}
+
+// Syntax-highlights source for the terminal, falling back to plain text
+func highlight(src, lexer string) string {
+	var b strings.Builder
+	if err := quick.Highlight(&b, src, lexer, "terminal256", "monokai"); err != nil {
+		return src
+	}
+	return b.String()
+}
+
+// Renders Markdown with Glamour wrapped to the given width
+func renderMarkdown(markdown string, width int) (string, error) {
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle("dark"),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return "", err
+	}
+	return renderer.Render(markdown)
+}
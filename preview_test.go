@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPreviewDebounce(t *testing.T) {
+	m := initialModel(defaultTiers)
+	if m.refreshPreview() != nil {
+		t.Error("refreshPreview scheduled a preview without a change")
+	}
+
+	// Two quick keystrokes: only the timer of the last one generates
+	m.inputs[0].SetValue("sh")
+	if m.refreshPreview() == nil {
+		t.Fatal("refreshPreview scheduled nothing after a change")
+	}
+	first := m.previewSeq
+	m.inputs[0].SetValue("shop")
+	m.refreshPreview()
+	if m.debouncedPreview(previewDebounceMsg{Seq: first}) != nil {
+		t.Error("a superseded timer generated a preview")
+	}
+	if m.debouncedPreview(previewDebounceMsg{Seq: m.previewSeq}) == nil {
+		t.Error("the latest timer generated no preview")
+	}
+}
+
+func TestApplyPreviewError(t *testing.T) {
+	m := initialModel(defaultTiers)
+	m.previewSummary = "decisions of older values"
+	m.applyPreview(PreviewMsg{Seq: m.previewSeq, Err: errors.New("invalid tier")})
+	if m.previewSummary != "" {
+		t.Errorf("summary kept after an error: %q", m.previewSummary)
+	}
+	if m.previewErr == nil {
+		t.Error("preview error was not recorded")
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Preemption policies a PriorityClass accepts
+var preemptionPolicies = []string{"PreemptLowerPriority", "Never"}
+
+// Highest value a user-defined PriorityClass may have, above it is reserved
+// for system classes
+const maxPriorityValue = 1000000000
+
+// priorityClassPolicy maps an importance level to a PriorityClass, either
+// generated or one the cluster already has.
+type priorityClassPolicy struct {
+	Name             string `yaml:"name"`
+	Value            int    `yaml:"value"`            // Only used when the class is generated
+	PreemptionPolicy string `yaml:"preemptionPolicy"` // PreemptLowerPriority or Never, only used when generated
+	Existing         bool   `yaml:"existing"`         // Reference a class managed elsewhere instead of generating it
+}
+
+// PrioritySpec represents the decided scheduling priority, empty when the
+// importance level has no PriorityClass.
+type PrioritySpec struct {
+	Class priorityClassPolicy
+}
+
+// Checks the policy's values
+func (policy priorityClassPolicy) validate() error {
+	if policy.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if policy.Existing {
+		return nil
+	}
+	if policy.Value < -maxPriorityValue || policy.Value > maxPriorityValue {
+		return fmt.Errorf("value must be within ±%d, got %d", maxPriorityValue, policy.Value)
+	}
+	if policy.PreemptionPolicy != "" && !slices.Contains(preemptionPolicies, policy.PreemptionPolicy) {
+		return fmt.Errorf("preemptionPolicy must be one of %v, got %q", preemptionPolicies, policy.PreemptionPolicy)
+	}
+	return nil
+}
+
+// Decides the PriorityClass the app's pods run with
+func (config *ConfigSpec) decidePriority(resultChan chan<- TimedResult) {
+	startTime := time.Now()
+
+	platform, err := loadPlatformConfig()
+	resultChan <- TimedResult{
+		Name:         "priority",
+		PrioritySpec: PrioritySpec{Class: platform.PriorityClasses[config.ImportanceLevel]},
+		Duration:     time.Since(startTime),
+		Error:        err,
+	}
+}
+
+// Renders the pod spec's priorityClassName, if the app has a class
+func priorityClassString(priority PrioritySpec) string {
+	if priority.Class.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf("      priorityClassName: %s\n", priority.Class.Name)
+}
+
+// Generates the cluster-wide PriorityClass of the app's importance level.
+// Apps of the same level generate the same document.
+func generatePriorityClass(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	class := timedResults["priority"].PrioritySpec.Class
+	preemptionPolicy := class.PreemptionPolicy
+	if preemptionPolicy == "" {
+		preemptionPolicy = "PreemptLowerPriority"
+	}
+	return fmt.Sprintf(`
+apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: %s
+value: %d
+globalDefault: false
+preemptionPolicy: %s
+description: "Pods of %s importance apps"
+`, class.Name, class.Value, preemptionPolicy, config.ImportanceLevel)
+}
+
+// Describes the priority for summaries
+func (priority PrioritySpec) String() string {
+	class := priority.Class
+	switch {
+	case class.Name == "":
+		return "Default"
+	case class.Existing:
+		return fmt.Sprintf("%s (existing class)", class.Name)
+	case class.PreemptionPolicy == "Never":
+		return fmt.Sprintf("%s (value %d, never preempts)", class.Name, class.Value)
+	}
+	return fmt.Sprintf("%s (value %d, preempts lower priority)", class.Name, class.Value)
+}
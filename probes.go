@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Probe is one health check of the container.
+type Probe struct {
+	Type             string // "http", "https", "tcp" or "grpc"
+	Port             ServicePort
+	Path             string // HTTP and HTTPS probes only
+	PeriodSeconds    int
+	TimeoutSeconds   int
+	FailureThreshold int
+}
+
+// ProbeSpec represents the decided health probes, all nil when the app has
+// no TCP port to probe.
+type ProbeSpec struct {
+	Liveness  *Probe
+	Readiness *Probe
+	Startup   *Probe
+}
+
+// probeTiming is how often and how patiently an importance tier is probed.
+type probeTiming struct {
+	PeriodSeconds    int `yaml:"periodSeconds"` // Readiness period, liveness runs at twice that
+	TimeoutSeconds   int `yaml:"timeoutSeconds"`
+	FailureThreshold int `yaml:"failureThreshold"`
+}
+
+// Timing of tiers that define none
+var defaultProbeTiming = probeTiming{PeriodSeconds: 10, TimeoutSeconds: 3, FailureThreshold: 3}
+
+// Checks the timing's values
+func (timing probeTiming) validate() error {
+	if timing.PeriodSeconds <= 0 || timing.TimeoutSeconds <= 0 || timing.FailureThreshold <= 0 {
+		return fmt.Errorf("periodSeconds, timeoutSeconds and failureThreshold must be positive")
+	}
+	if timing.TimeoutSeconds > timing.PeriodSeconds {
+		return fmt.Errorf("timeoutSeconds must not exceed periodSeconds, got %d and %d", timing.TimeoutSeconds, timing.PeriodSeconds)
+	}
+	return nil
+}
+
+// Startup time assumed when the spec does not state one, by runtime
+var defaultStartupSeconds = map[string]int{
+	"jvm":    60,
+	"go":     5,
+	"node":   10,
+	"python": 15,
+	"":       30,
+}
+
+// Health endpoints runtimes conventionally serve, Spring Boot for the JVM
+var defaultProbePaths = map[string][2]string{
+	"jvm": {"/actuator/health/liveness", "/actuator/health/readiness"},
+	"":    {"/healthz", "/readyz"},
+}
+
+// Decides the liveness, readiness and startup probes: gRPC health checks for
+// gRPC ports, HTTP for HTTP ports and TCP connects for anything else
+func (config *ConfigSpec) decideProbes(resultChan chan<- TimedResult) {
+	startTime := time.Now()
+	time.Sleep(time.Millisecond * 50) // Simulate some computation
+
+	ports, err := config.decidePorts()
+	if err != nil {
+		resultChan <- TimedResult{Name: "probes", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+
+	tier, err := config.tier()
+	if err != nil {
+		resultChan <- TimedResult{Name: "probes", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+
+	var probes ProbeSpec
+	if port, ok := servingPort(ports); ok {
+		timing := defaultProbeTiming
+		if tier.Probes != nil {
+			timing = *tier.Probes
+		}
+		livenessPath, readinessPath := config.probePaths()
+
+		probe := Probe{Type: probeType(port), Port: port, TimeoutSeconds: timing.TimeoutSeconds, FailureThreshold: timing.FailureThreshold}
+		readiness := probe
+		readiness.Path = readinessPath
+		readiness.PeriodSeconds = timing.PeriodSeconds
+		liveness := probe
+		liveness.Path = livenessPath
+		liveness.PeriodSeconds = timing.PeriodSeconds * 2
+
+		// The startup probe holds the others off until the app is up, with
+		// half the startup time again as slack
+		startup := liveness
+		startup.PeriodSeconds = timing.PeriodSeconds
+		startup.FailureThreshold = max(3, int(math.Ceil(float64(config.startupSeconds())*1.5/float64(timing.PeriodSeconds))))
+
+		probes = ProbeSpec{Liveness: &liveness, Readiness: &readiness, Startup: &startup}
+	}
+
+	resultChan <- TimedResult{
+		Name:      "probes",
+		ProbeSpec: probes,
+		Duration:  time.Since(startTime),
+	}
+}
+
+// The port health checks and public traffic go to: the first TCP port that
+// is neither HTTPS nor metrics, falling back to the first TCP port
+func servingPort(ports []ServicePort) (ServicePort, bool) {
+	var fallback *ServicePort
+	for i, port := range ports {
+		if port.Protocol != "TCP" {
+			continue
+		}
+		if !isHTTPSPort(port) && port.Name != metricsPort.Name {
+			return port, true
+		}
+		if fallback == nil {
+			fallback = &ports[i]
+		}
+	}
+	if fallback == nil {
+		return ServicePort{}, false
+	}
+	return *fallback, true
+}
+
+// Picks the probe type a port understands. HTTPS ports are probed over TLS,
+// the kubelet skips certificate verification.
+func probeType(port ServicePort) string {
+	switch {
+	case port.AppProtocol == "grpc" || port.Name == "grpc" || strings.HasPrefix(port.Name, "grpc-"):
+		return "grpc"
+	case port.AppProtocol == "https" || isHTTPSPort(port):
+		return "https"
+	case port.AppProtocol == "http" || port.AppProtocol == "h2c" || port.AppProtocol == "kubernetes.io/h2c" ||
+		port.Name == "http" || strings.HasPrefix(port.Name, "http-"):
+		return "http"
+	}
+	return "tcp"
+}
+
+// Liveness and readiness paths, the spec's overrides or the runtime's conventions
+func (config *ConfigSpec) probePaths() (string, string) {
+	paths, ok := defaultProbePaths[config.Runtime]
+	if !ok {
+		paths = defaultProbePaths[""]
+	}
+	liveness, readiness := paths[0], paths[1]
+	if config.LivenessPath != "" {
+		liveness = config.LivenessPath
+	}
+	if config.ReadinessPath != "" {
+		readiness = config.ReadinessPath
+	}
+	return liveness, readiness
+}
+
+// Seconds the app takes to start, stated or assumed from the runtime
+func (config *ConfigSpec) startupSeconds() int {
+	if config.StartupSeconds > 0 {
+		return config.StartupSeconds
+	}
+	if seconds, ok := defaultStartupSeconds[config.Runtime]; ok {
+		return seconds
+	}
+	return defaultStartupSeconds[""]
+}
+
+// Renders the probes as container fields
+func probesString(probes ProbeSpec) string {
+	var sb strings.Builder
+	for _, named := range []struct {
+		field string
+		probe *Probe
+	}{
+		{"startupProbe", probes.Startup},
+		{"livenessProbe", probes.Liveness},
+		{"readinessProbe", probes.Readiness},
+	} {
+		if named.probe == nil {
+			continue
+		}
+		probe := named.probe
+		sb.WriteString(fmt.Sprintf("        %s:\n", named.field))
+		switch probe.Type {
+		case "http":
+			sb.WriteString(fmt.Sprintf("          httpGet:\n            path: %s\n            port: %s\n", probe.Path, probe.Port.Name))
+		case "https":
+			sb.WriteString(fmt.Sprintf("          httpGet:\n            path: %s\n            port: %s\n            scheme: HTTPS\n", probe.Path, probe.Port.Name))
+		case "grpc":
+			// gRPC probes only take port numbers
+			sb.WriteString(fmt.Sprintf("          grpc:\n            port: %d\n", probe.Port.Port))
+		default:
+			sb.WriteString(fmt.Sprintf("          tcpSocket:\n            port: %s\n", probe.Port.Name))
+		}
+		sb.WriteString(fmt.Sprintf("          periodSeconds: %d\n          timeoutSeconds: %d\n          failureThreshold: %d\n",
+			probe.PeriodSeconds, probe.TimeoutSeconds, probe.FailureThreshold))
+	}
+	return sb.String()
+}
+
+// Describes a probe for summaries, e.g. "HTTP /readyz on http every 10s"
+func (probe *Probe) String() string {
+	target := fmt.Sprintf("on %s", probe.Port.Name)
+	if probe.Type == "http" || probe.Type == "https" {
+		target = fmt.Sprintf("%s %s", probe.Path, target)
+	}
+	return fmt.Sprintf("%s %s every %ds", strings.ToUpper(probe.Type), target, probe.PeriodSeconds)
+}
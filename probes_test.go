@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestProbeType(t *testing.T) {
+	tests := []struct {
+		port ServicePort
+		want string
+	}{
+		{ServicePort{Name: "grpc", Port: 9000}, "grpc"},
+		{ServicePort{Name: "api", Port: 9000, AppProtocol: "grpc"}, "grpc"},
+		{ServicePort{Name: "web", Port: 8080, AppProtocol: "http"}, "http"},
+		{ServicePort{Name: "web", Port: 8080, AppProtocol: "h2c"}, "http"},
+		{ServicePort{Name: "web", Port: 8080, AppProtocol: "kubernetes.io/h2c"}, "http"},
+		{ServicePort{Name: "http-alt", Port: 8081}, "http"},
+		{ServicePort{Name: "web", Port: 8443, AppProtocol: "https"}, "https"},
+		{ServicePort{Name: "https", Port: 8443}, "https"},
+		{ServicePort{Name: "tls", Port: 443}, "https"},
+		{ServicePort{Name: "web", Port: 8080, AppProtocol: "http2"}, "tcp"},
+		{ServicePort{Name: "redis", Port: 6379}, "tcp"},
+	}
+	for _, test := range tests {
+		if got := probeType(test.port); got != test.want {
+			t.Errorf("probeType(%v) = %s, want %s", test.port, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Memory quantity suffixes and their size in MiB
+var memoryUnits = []struct {
+	suffix string
+	mi     float64
+}{
+	{"Ki", 1.0 / 1024},
+	{"Mi", 1},
+	{"Gi", 1024},
+	{"Ti", 1024 * 1024},
+	{"k", 1e3 / (1 << 20)},
+	{"M", 1e6 / (1 << 20)},
+	{"G", 1e9 / (1 << 20)},
+	{"T", 1e12 / (1 << 20)},
+}
+
+// Parses a Kubernetes CPU quantity such as "500m" or "1.5" into cores
+func parseCPU(quantity string) (float64, error) {
+	quantity = strings.TrimSpace(quantity)
+	if milli, ok := strings.CutSuffix(quantity, "m"); ok {
+		value, err := strconv.ParseFloat(milli, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU quantity %q", quantity)
+		}
+		return value / 1000, nil
+	}
+
+	value, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU quantity %q", quantity)
+	}
+	return value, nil
+}
+
+// Parses a Kubernetes memory or storage quantity such as "512Mi", "1Gi" or
+// "1G" into MiB. Plain numbers are bytes.
+func parseMemoryMi(quantity string) (float64, error) {
+	quantity = strings.TrimSpace(quantity)
+	for _, unit := range memoryUnits {
+		if number, ok := strings.CutSuffix(quantity, unit.suffix); ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid memory quantity %q", quantity)
+			}
+			return value * unit.mi, nil
+		}
+	}
+
+	value, err := strconv.ParseFloat(quantity, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory quantity %q", quantity)
+	}
+	return value / (1 << 20), nil
+}
+
+// Parses a bandwidth such as "200Mbps" or "200M" into Mbps
+func parseBandwidthMbps(bandwidth string) (float64, error) {
+	number := strings.TrimSuffix(strings.TrimSpace(bandwidth), "bps")
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(number, "G"):
+		scale = 1000
+	case strings.HasSuffix(number, "K"), strings.HasSuffix(number, "k"):
+		scale = 0.001
+	}
+	number = strings.TrimRight(number, "GMKk")
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q", bandwidth)
+	}
+	return value * scale, nil
+}
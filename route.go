@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RouteSpec is the decided public exposure of the app.
+type RouteSpec struct {
+	Kind             string // "Ingress" or "HTTPRoute"
+	Host             string
+	Path             string
+	TLS              bool   // Terminate TLS for the host, decided by port 443
+	Gateway          string // Gateway the HTTPRoute attaches to
+	GatewayNamespace string // Empty for the app's namespace
+	BackendPort      string // Name of the Service port traffic is routed to
+}
+
+// Decides how the app is exposed publicly, nil when no hostname is given.
+// An HTTPS port asks for TLS, which the route terminates so the backend is
+// the serving port.
+func (config *ConfigSpec) decideRoute(ports []ServicePort) *RouteSpec {
+	if config.Hostname == "" {
+		return nil
+	}
+
+	route := &RouteSpec{
+		Kind: "Ingress",
+		Host: config.Hostname,
+		Path: config.Path,
+		TLS:  slices.ContainsFunc(ports, isHTTPSPort),
+	}
+	if route.Path == "" {
+		route.Path = "/"
+	}
+	if config.Gateway != "" {
+		route.Kind = "HTTPRoute"
+		route.Gateway = config.Gateway
+		if namespace, name, ok := strings.Cut(config.Gateway, "/"); ok {
+			route.GatewayNamespace, route.Gateway = namespace, name
+		}
+	}
+
+	route.BackendPort = ports[0].Name
+	if port, ok := servingPort(ports); ok {
+		route.BackendPort = port.Name
+	}
+	return route
+}
+
+// Checks the exposure inputs: a bare hostname, an absolute path and a
+// gateway reference, the latter two only alongside a hostname
+func validateRoute(hostname, path, gateway string) error {
+	if hostname == "" && (path != "" || gateway != "") {
+		return fmt.Errorf("path and gateway need a hostname to expose the app on")
+	}
+	if strings.ContainsAny(hostname, ":/ ") {
+		return fmt.Errorf("hostname must be a bare host such as shop.example.com, got %q", hostname)
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("path must start with /, got %q", path)
+	}
+	if strings.Count(gateway, "/") > 1 || strings.HasPrefix(gateway, "/") || strings.HasSuffix(gateway, "/") {
+		return fmt.Errorf("gateway must be name or namespace/name, got %q", gateway)
+	}
+	return nil
+}
+
+// Generates the ClusterIP Service in front of the app's pods
+func generateService(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	var ports strings.Builder
+	for _, port := range timedResults["network"].NetworkSpec.Ports {
+		ports.WriteString(fmt.Sprintf("    - name: %s\n      port: %d\n      targetPort: %s\n      protocol: %s\n",
+			port.Name, port.Port, port.Name, port.Protocol))
+		if port.AppProtocol != "" {
+			ports.WriteString(fmt.Sprintf("      appProtocol: %s\n", port.AppProtocol))
+		}
+	}
+
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: %s-service
+spec:
+  type: ClusterIP
+  selector:
+    app: %s
+  ports:
+%s`, config.AppName, config.AppName, ports.String())
+}
+
+// Generates the Ingress or HTTPRoute exposing the app's Service
+func generateRoute(config *ConfigSpec, timedResults map[string]TimedResult) string {
+	network := timedResults["network"].NetworkSpec
+	route := network.Route
+	if route.Kind == "HTTPRoute" {
+		return generateHTTPRoute(config, route, network.Ports)
+	}
+	return generateIngress(config, route)
+}
+
+// Name of the TLS secret holding the certificate for the app's host
+func tlsSecretName(config *ConfigSpec) string {
+	return fmt.Sprintf("%s-tls", config.AppName)
+}
+
+func generateIngress(config *ConfigSpec, route *RouteSpec) string {
+	tls := ""
+	if route.TLS {
+		tls = fmt.Sprintf(`
+  tls:
+    - hosts:
+        - %s
+      secretName: %s`, route.Host, tlsSecretName(config))
+	}
+
+	return fmt.Sprintf(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: %s-route
+spec:%s
+  rules:
+    - host: %s
+      http:
+        paths:
+          - path: %s
+            pathType: Prefix
+            backend:
+              service:
+                name: %s-service
+                port:
+                  name: %s
+`, config.AppName, tls, route.Host, route.Path, config.AppName, route.BackendPort)
+}
+
+// The Gateway API puts TLS on the Gateway's listener rather than the route,
+// so with TLS the route attaches to the listener named "https" and the
+// certificate has to be referenced there
+func generateHTTPRoute(config *ConfigSpec, route *RouteSpec, ports []ServicePort) string {
+	// backendRefs only take port numbers
+	var backendPort int
+	for _, port := range ports {
+		if port.Name == route.BackendPort {
+			backendPort = port.Port
+		}
+	}
+
+	parent := fmt.Sprintf("    - name: %s\n", route.Gateway)
+	if route.GatewayNamespace != "" {
+		parent += fmt.Sprintf("      namespace: %s\n", route.GatewayNamespace)
+	}
+	if route.TLS {
+		parent += fmt.Sprintf("      sectionName: https # Listener with certificateRefs to secret %s\n", tlsSecretName(config))
+	}
+
+	return fmt.Sprintf(`
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata:
+  name: %s-route
+spec:
+  parentRefs:
+%s  hostnames:
+    - %s
+  rules:
+    - matches:
+        - path:
+            type: PathPrefix
+            value: %s
+      backendRefs:
+        - name: %s-service
+          port: %d
+`, config.AppName, parent, route.Host, route.Path, config.AppName, backendPort)
+}
+
+// Describes where the route serves the app, e.g. "https://shop.example.com/api"
+func (route *RouteSpec) url() string {
+	scheme := "http"
+	if route.TLS {
+		scheme = "https"
+	}
+	return scheme + "://" + route.Host + route.Path
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// EnvVar is an environment variable set on the generated container, by the
+// runtime or as a plain value of the spec.
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// runtimeProfile describes how a language runtime turns the memory the app
+// needs into the memory the container needs.
+type runtimeProfile struct {
+	overheadMi int     // Memory outside the heap: code, metadata, thread stacks
+	gcHeadroom float64 // Extra heap the collector needs on top of live data
+	env        func(limitMi, heapMi int) []EnvVar
+}
+
+// Memory limits are rounded up to this many Mi
+const memoryStepMi = 32
+
+// Runtime memory models, keyed by the runtime field of the spec
+var runtimeProfiles = map[string]runtimeProfile{
+	// Metaspace, code cache and thread stacks live outside the heap, and G1
+	// wants half as much free heap again as there is live data
+	"jvm": {
+		overheadMi: 128,
+		gcHeadroom: 0.5,
+		env: func(limitMi, heapMi int) []EnvVar {
+			percentage := heapMi * 100 / limitMi
+			return []EnvVar{{"JAVA_TOOL_OPTIONS", fmt.Sprintf("-XX:MaxRAMPercentage=%d -XX:+ExitOnOutOfMemoryError", percentage)}}
+		},
+	},
+	// With a soft limit the collector runs harder near it instead of letting
+	// the heap double, so a quarter on top of live data is enough
+	"go": {
+		overheadMi: 16,
+		gcHeadroom: 0.25,
+		env: func(limitMi, heapMi int) []EnvVar {
+			return []EnvVar{{"GOMEMLIMIT", fmt.Sprintf("%dMiB", limitMi*9/10)}}
+		},
+	},
+	// V8 sizes its old space independently of the container, so it has to be
+	// capped or it grows past the limit before collecting
+	"node": {
+		overheadMi: 64,
+		gcHeadroom: 0.5,
+		env: func(limitMi, heapMi int) []EnvVar {
+			return []EnvVar{{"NODE_OPTIONS", fmt.Sprintf("--max-old-space-size=%d", heapMi)}}
+		},
+	},
+	// Reference counting frees memory promptly, fragmentation across glibc
+	// malloc arenas is what grows instead
+	"python": {
+		overheadMi: 32,
+		gcHeadroom: 0.1,
+		env: func(limitMi, heapMi int) []EnvVar {
+			return []EnvVar{{"MALLOC_ARENA_MAX", "2"}}
+		},
+	},
+}
+
+// Names of the supported runtimes, sorted
+func runtimeNames() []string {
+	names := make([]string, 0, len(runtimeProfiles))
+	for name := range runtimeProfiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Sizes the container memory limit in Mi for the runtime from the memory the
+// app itself needs. Without a runtime the app memory is used as is.
+func (config *ConfigSpec) runtimeMemoryLimit(appMi float64) int {
+	profile, ok := runtimeProfiles[config.Runtime]
+	if !ok {
+		return int(math.Ceil(appMi))
+	}
+	limitMi := int(math.Ceil(appMi*(1+profile.gcHeadroom))) + profile.overheadMi
+	return (limitMi + memoryStepMi - 1) / memoryStepMi * memoryStepMi
+}
+
+// Env vars that keep the runtime within the memory limit, giving the heap
+// everything but the runtime's overhead
+func (config *ConfigSpec) runtimeEnv(limitMi int) []EnvVar {
+	profile, ok := runtimeProfiles[config.Runtime]
+	if !ok {
+		return nil
+	}
+	return profile.env(limitMi, limitMi-profile.overheadMi)
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPI definitions of the generated kinds, see the file's info for what
+// it covers
+//
+//go:embed schemas/kubernetes.json
+var kubernetesSchemaJSON []byte
+
+// Kubernetes minor versions the bundled schemas describe, oldest first
+var kubernetesVersions = []string{"1.27", "1.28", "1.29", "1.30", "1.31", "1.32", "1.33", "1.34"}
+
+// Serialized Kubernetes resource quantity, e.g. "500m", "1.5Gi" or "1e3"
+var quantityPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([KMGTPE]i|[numkMGTPE]|[eE][+-]?[0-9]+)?$`)
+
+// openAPISchema is the part of an OpenAPI v2 document validation needs.
+type openAPISchema struct {
+	Definitions map[string]*schemaNode `json:"definitions"`
+}
+
+// schemaNode is one OpenAPI schema, as a definition or nested in one.
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Format               string                 `json:"format"` // "int-or-string" and "quantity" accept more than their type
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *schemaNode            `json:"additionalProperties"` // Value schema of map objects
+	Items                *schemaNode            `json:"items"`
+	Required             []string               `json:"required"`
+	Enum                 []string               `json:"enum"`
+	Since                string                 `json:"x-tiny-workloads-since"` // First minor version serving the field
+	GroupVersionKinds    []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	} `json:"x-kubernetes-group-version-kind"`
+}
+
+// schemaViolation is a field of an object that does not match its schema.
+type schemaViolation struct {
+	Path    string // e.g. spec.template.spec.containers[0].ports[0].containerPort
+	Line    int
+	Message string
+}
+
+// objectValidation is the outcome of validating one object of a manifest.
+type objectValidation struct {
+	File       string
+	Kind       string
+	Name       string
+	Unchecked  bool // No bundled schema describes the object's apiVersion and kind
+	Violations []schemaViolation
+}
+
+// schemaValidator walks a YAML document along its schema, collecting the
+// violations for one Kubernetes version.
+type schemaValidator struct {
+	schema     *openAPISchema
+	minor      int
+	violations []schemaViolation
+}
+
+// Parses the bundled schemas
+func loadKubernetesSchema() (*openAPISchema, error) {
+	var schema openAPISchema
+	if err := json.Unmarshal(kubernetesSchemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("error parsing bundled schemas: %v", err)
+	}
+	return &schema, nil
+}
+
+// Resolves the Kubernetes version to validate against: the given one, else
+// the platform config's, else the newest bundled. Patch versions and a "v"
+// prefix are accepted, e.g. "v1.30.2" is "1.30".
+func resolveKubernetesVersion(version string) (string, error) {
+	if version == "" {
+		platform, err := loadPlatformConfig()
+		if err != nil {
+			return "", err
+		}
+		version = platform.KubernetesVersion
+	}
+	if version == "" {
+		return kubernetesVersions[len(kubernetesVersions)-1], nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) >= 2 {
+		version = parts[0] + "." + parts[1]
+	}
+	if !slices.Contains(kubernetesVersions, version) {
+		return "", fmt.Errorf("no bundled schemas for Kubernetes %s, supported are %s to %s",
+			version, kubernetesVersions[0], kubernetesVersions[len(kubernetesVersions)-1])
+	}
+	return version, nil
+}
+
+// Minor number of a "1.x" version
+func minorVersion(version string) int {
+	minor, _ := strconv.Atoi(strings.TrimPrefix(version, "1."))
+	return minor
+}
+
+// Validates every object of a multi-document manifest against the schemas
+// of a supported Kubernetes version
+func validateManifest(file, content, version string) ([]objectValidation, error) {
+	schema, err := loadKubernetesSchema()
+	if err != nil {
+		return nil, err
+	}
+	byKind := make(map[string]*schemaNode)
+	for _, definition := range schema.Definitions {
+		for _, gvk := range definition.GroupVersionKinds {
+			byKind[groupVersion(gvk.Group, gvk.Version)+"/"+gvk.Kind] = definition
+		}
+	}
+
+	objects, err := decodeObjects(file, content)
+	if err != nil {
+		return nil, err
+	}
+	var results []objectValidation
+	for _, root := range objects {
+		result := objectValidation{
+			File: file,
+			Kind: scalarValue(mappingValue(root, "kind")),
+			Name: scalarValue(mappingValue(mappingValue(root, "metadata"), "name")),
+		}
+		definition, ok := byKind[scalarValue(mappingValue(root, "apiVersion"))+"/"+result.Kind]
+		if !ok {
+			result.Unchecked = true
+			results = append(results, result)
+			continue
+		}
+		validator := schemaValidator{schema: schema, minor: minorVersion(version)}
+		validator.check(root, definition, "")
+		result.Violations = validator.violations
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Parses the objects of a multi-document manifest, skipping empty documents
+func decodeObjects(file, content string) ([]*yaml.Node, error) {
+	var objects []*yaml.Node
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var node yaml.Node
+		err := decoder.Decode(&node)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", file, err)
+		}
+		if root := documentRoot(&node); root.Kind == yaml.MappingNode {
+			objects = append(objects, root)
+		}
+	}
+	return objects, nil
+}
+
+// Validates the generated documents
+func validateDocuments(docs []manifestDocument, version string) ([]objectValidation, error) {
+	var results []objectValidation
+	for _, doc := range docs {
+		docResults, err := validateManifest(doc.File, doc.Content, version)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, docResults...)
+	}
+	return results, nil
+}
+
+// apiVersion of a group and version, without a group for the core API
+func groupVersion(group, version string) string {
+	if group == "" {
+		return version
+	}
+	return group + "/" + version
+}
+
+// Records a violation at a node
+func (v *schemaValidator) report(node *yaml.Node, path, format string, args ...any) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.violations = append(v.violations, schemaViolation{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+// Checks a node against its schema, descending into objects and arrays
+func (v *schemaValidator) check(node *yaml.Node, schema *schemaNode, path string) {
+	for schema.Ref != "" {
+		schema = v.schema.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")]
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return // Same as leaving the field out
+	}
+
+	switch {
+	case schema.Format == "int-or-string":
+		if node.Tag != "!!int" && node.Tag != "!!str" {
+			v.report(node, path, "expected integer or string, got %s", yamlType(node))
+		}
+		return
+	case schema.Format == "quantity":
+		if node.Tag != "!!int" && node.Tag != "!!float" && node.Tag != "!!str" {
+			v.report(node, path, "expected quantity, got %s", yamlType(node))
+		} else if !quantityPattern.MatchString(node.Value) {
+			v.report(node, path, "%q is not a valid quantity", node.Value)
+		}
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			v.report(node, path, "expected object, got %s", yamlType(node))
+			return
+		}
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			seen[key.Value] = true
+			fieldPath := joinFieldPath(path, key.Value)
+			if property, ok := schema.Properties[key.Value]; ok {
+				if property.Since != "" && minorVersion(property.Since) > v.minor {
+					v.report(key, fieldPath, "field is not available before Kubernetes %s", property.Since)
+					continue
+				}
+				v.check(value, property, fieldPath)
+			} else if schema.AdditionalProperties != nil {
+				v.check(value, schema.AdditionalProperties, fieldPath)
+			} else if len(schema.Properties) > 0 {
+				v.report(key, fieldPath, "unknown field %q", key.Value)
+			}
+		}
+		for _, required := range schema.Required {
+			if !seen[required] {
+				v.report(node, path, "missing required field %q", required)
+			}
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, path, "expected array, got %s", yamlType(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.check(item, schema.Items, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case "string":
+		if node.Kind == yaml.ScalarNode && node.Tag != "!!str" {
+			v.report(node, path, "expected string, got %s %s, quote it", yamlType(node), node.Value)
+		} else if node.Kind != yaml.ScalarNode {
+			v.report(node, path, "expected string, got %s", yamlType(node))
+		} else if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, node.Value) {
+			v.report(node, path, "%q is not one of %s", node.Value, strings.Join(schema.Enum, ", "))
+		}
+	case "integer":
+		if node.Tag != "!!int" {
+			v.report(node, path, "expected integer, got %s", yamlType(node))
+		}
+	case "number":
+		if node.Tag != "!!int" && node.Tag != "!!float" {
+			v.report(node, path, "expected number, got %s", yamlType(node))
+		}
+	case "boolean":
+		if node.Tag != "!!bool" {
+			v.report(node, path, "expected boolean, got %s", yamlType(node))
+		}
+	}
+}
+
+// Path of a field below an object
+func joinFieldPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// Describes the type of a YAML node for messages
+func yamlType(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	}
+	switch node.Tag {
+	case "!!int":
+		return "integer"
+	case "!!float":
+		return "number"
+	case "!!bool":
+		return "boolean"
+	case "!!str":
+		return fmt.Sprintf("string %q", node.Value)
+	}
+	return strings.TrimPrefix(node.Tag, "!!")
+}
+
+// Number of objects with violations
+func invalidObjects(results []objectValidation) int {
+	invalid := 0
+	for _, result := range results {
+		if len(result.Violations) > 0 {
+			invalid++
+		}
+	}
+	return invalid
+}
+
+// Describes the validation results as Markdown
+func validationMarkdown(results []objectValidation, version string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n## Schema Validation (Kubernetes %s)\n\n", version))
+	checked := 0
+	for _, result := range results {
+		switch {
+		case result.Unchecked:
+			sb.WriteString(fmt.Sprintf("- %s %s in `%s` not checked, no bundled schema for its kind\n", result.Kind, result.Name, result.File))
+		case len(result.Violations) > 0:
+			sb.WriteString(fmt.Sprintf("- **%s %s** in `%s`:\n", result.Kind, result.Name, result.File))
+			for _, violation := range result.Violations {
+				sb.WriteString(fmt.Sprintf("  - `%s` (line %d): %s\n", violation.Path, violation.Line, violation.Message))
+			}
+		default:
+			checked++
+		}
+	}
+	if checked > 0 {
+		sb.WriteString(fmt.Sprintf("- %d object(s) valid\n", checked))
+	}
+	return sb.String()
+}
+
+// Validates the generated objects for the model's Kubernetes version,
+// resolving the version first, and evaluates the policies against them
+func (m *model) validate() error {
+	version, err := resolveKubernetesVersion(m.kubeVersion)
+	if err != nil {
+		return err
+	}
+	docs := generateManifests(&m.config, m.result)
+	validation, err := validateDocuments(docs, version)
+	if err != nil {
+		return err
+	}
+	rules, err := loadPolicies()
+	if err != nil {
+		return err
+	}
+	violations, err := evaluatePolicies(rules, docs)
+	if err != nil {
+		return err
+	}
+	m.kubeVersion, m.validation = version, validation
+	m.policyRules, m.policyViolations = rules, violations
+	return nil
+}
+
+// Describes the findings that block writing: objects failing schema
+// validation and policy violations of error severity, empty if there are none
+func (m *model) blockingFindings() string {
+	var findings []string
+	if invalid := invalidObjects(m.validation); invalid > 0 {
+		findings = append(findings, fmt.Sprintf("%d generated object(s) fail schema validation for Kubernetes %s", invalid, m.kubeVersion))
+	}
+	if errors := policyErrors(m.policyViolations); errors > 0 {
+		findings = append(findings, fmt.Sprintf("%d policy violation(s) of error severity", errors))
+	}
+	return strings.Join(findings, " and ")
+}
+
+// Describes the schema validation and policy checks as Markdown
+func (m *model) findingsMarkdown() string {
+	var sb strings.Builder
+	if m.validation != nil {
+		sb.WriteString(validationMarkdown(m.validation, m.kubeVersion))
+	}
+	if len(m.policyRules) > 0 {
+		sb.WriteString(policyMarkdown(m.policyRules, m.policyViolations))
+	}
+	return sb.String()
+}
+
+// Entry point of the validate subcommand: checks manifest files against the
+// bundled schemas and the policies, the generated ones if none are given
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	kubeVersion := flags.String("kube-version", "", "Kubernetes version to validate for, the platform config's or the newest bundled if unset")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: tiny-workloads validate [-kube-version 1.30] [manifest.yaml...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	version, err := resolveKubernetesVersion(*kubeVersion)
+	if err != nil {
+		return err
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths, err = filepath.Glob(filepath.Join(manifestDir, "*.yaml"))
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no manifests in %s, pass the files to validate", manifestDir)
+		}
+	}
+
+	var results []objectValidation
+	var docs []manifestDocument
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading manifest: %v", err)
+		}
+		fileResults, err := validateManifest(path, string(content), version)
+		if err != nil {
+			return err
+		}
+		results = append(results, fileResults...)
+		docs = append(docs, manifestDocument{File: path, Content: string(content)})
+	}
+	rules, err := loadPolicies()
+	if err != nil {
+		return err
+	}
+	violations, err := evaluatePolicies(rules, docs)
+	if err != nil {
+		return err
+	}
+
+	markdown := validationMarkdown(results, version)
+	if len(rules) > 0 {
+		markdown += policyMarkdown(rules, violations)
+	}
+	printMarkdown(markdown)
+	if invalid := invalidObjects(results); invalid > 0 {
+		return fmt.Errorf("%d object(s) fail schema validation for Kubernetes %s", invalid, version)
+	}
+	if errors := policyErrors(violations); errors > 0 {
+		return fmt.Errorf("%d policy violation(s) of error severity", errors)
+	}
+	return nil
+}
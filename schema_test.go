@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		version       string
+		want          []string // Violations as path: message
+		wantUnchecked bool
+	}{
+		{
+			name:    "valid service",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: shop\nspec:\n  ports:\n    - port: 80\n      targetPort: http\n",
+			version: "1.30",
+		},
+		{
+			name:    "wrong types and unknown field",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: shop\nspec:\n  ports:\n    - port: \"80\"\n      targetPort: [http]\n  typo: true\n",
+			version: "1.30",
+			want: []string{
+				`spec.ports[0].port: expected integer, got string "80"`,
+				"spec.ports[0].targetPort: expected integer or string, got array",
+				`spec.typo: unknown field "typo"`,
+			},
+		},
+		{
+			name:    "missing required field and bad enum",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: shop\nspec:\n  type: Public\n  ports:\n    - name: http\n",
+			version: "1.30",
+			want: []string{
+				`spec.type: "Public" is not one of ClusterIP, NodePort, LoadBalancer, ExternalName`,
+				`spec.ports[0]: missing required field "port"`,
+			},
+		},
+		{
+			name:    "invalid quantity and unquoted string",
+			content: "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: data\nspec:\n  storageClassName: 12\n  resources:\n    requests:\n      storage: ten gigs\n",
+			version: "1.30",
+			want: []string{
+				"spec.storageClassName: expected string, got integer 12, quote it",
+				`spec.resources.requests.storage: "ten gigs" is not a valid quantity`,
+			},
+		},
+		{
+			name:    "field newer than the version",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: shop\nspec:\n  trafficDistribution: PreferClose\n",
+			version: "1.29",
+			want:    []string{"spec.trafficDistribution: field is not available before Kubernetes 1.30"},
+		},
+		{
+			name:    "same field once available",
+			content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: shop\nspec:\n  trafficDistribution: PreferClose\n",
+			version: "1.30",
+		},
+		{
+			name:          "kind without a bundled schema",
+			content:       "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\n",
+			version:       "1.30",
+			wantUnchecked: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, err := validateManifest("test.yaml", test.content, test.version)
+			if err != nil {
+				t.Fatalf("validateManifest: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d objects, want 1", len(results))
+			}
+			if results[0].Unchecked != test.wantUnchecked {
+				t.Errorf("unchecked = %v, want %v", results[0].Unchecked, test.wantUnchecked)
+			}
+			var got []string
+			for _, violation := range results[0].Violations {
+				got = append(got, violation.Path+": "+violation.Message)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("violations = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantErr bool
+	}{
+		{version: "1.30", want: "1.30"},
+		{version: "v1.31.2", want: "1.31"},
+		{version: "1.20", wantErr: true},
+		{version: "2.0", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := resolveKubernetesVersion(test.version)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("resolveKubernetesVersion(%q) = %q, %v, want %q, error %v", test.version, got, err, test.want, test.wantErr)
+		}
+	}
+}
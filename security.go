@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Pod Security Standards levels, from least to most locked down
+var podSecurityLevels = []string{"privileged", "baseline", "restricted"}
+
+// Level used when the spec does not choose one
+const defaultPodSecurityLevel = "restricted"
+
+// Ports below this need NET_BIND_SERVICE once all capabilities are dropped
+const privilegedPortLimit = 1024
+
+// SecuritySpec represents the decided security context and how it fares
+// against the Pod Security Standard of the chosen level.
+type SecuritySpec struct {
+	Level                  string
+	RunAsNonRoot           bool
+	SeccompRuntimeDefault  bool
+	DisallowEscalation     bool
+	ReadOnlyRootFilesystem bool // Comes with an emptyDir mounted at /tmp
+	DropAllCapabilities    bool
+	AddCapabilities        []string // Added back after dropping all
+	Checks                 []securityCheck
+}
+
+// securityCheck is one Pod Security Standard rule and whether the generated
+// pod satisfies it.
+type securityCheck struct {
+	Level     string // Level that introduces the rule
+	Rule      string
+	Satisfied bool
+	Detail    string // What the rule requires
+}
+
+// Pod security level of the spec, restricted if unset
+func (config *ConfigSpec) podSecurityLevel() string {
+	if config.PodSecurityLevel == "" {
+		return defaultPodSecurityLevel
+	}
+	return config.PodSecurityLevel
+}
+
+// Decides the pod and container security context for the chosen Pod
+// Security Standard level and checks the generated pod against its rules.
+// Restricted also gets a read-only root filesystem, which the standard does
+// not require but our hardening does.
+func (config *ConfigSpec) decideSecurity(resultChan chan<- TimedResult) {
+	startTime := time.Now()
+
+	ports, err := config.decidePorts()
+	if err != nil {
+		resultChan <- TimedResult{Name: "security", Error: err, Duration: time.Since(startTime)}
+		return
+	}
+
+	security := SecuritySpec{Level: config.podSecurityLevel()}
+	if security.Level == "restricted" {
+		security.RunAsNonRoot = true
+		security.SeccompRuntimeDefault = true
+		security.DisallowEscalation = true
+		security.ReadOnlyRootFilesystem = true
+		security.DropAllCapabilities = true
+		if slices.ContainsFunc(ports, func(port ServicePort) bool { return port.Port < privilegedPortLimit }) {
+			security.AddCapabilities = []string{"NET_BIND_SERVICE"}
+		}
+	}
+	security.Checks = security.check()
+
+	resultChan <- TimedResult{
+		Name:         "security",
+		SecuritySpec: security,
+		Duration:     time.Since(startTime),
+	}
+}
+
+// Checks the generated pod against the rules of the baseline and restricted
+// standards, whichever level was chosen. The generator never uses host
+// namespaces, host paths, host ports or privileged containers, so those
+// rules always hold.
+func (security SecuritySpec) check() []securityCheck {
+	checks := []securityCheck{
+		{"baseline", "Host namespaces", true, "hostNetwork, hostPID and hostIPC unset"},
+		{"baseline", "Privileged containers", true, "privileged unset"},
+		{"baseline", "HostPath volumes", true, "no hostPath volumes"},
+		{"baseline", "Host ports", true, "no hostPort on container ports"},
+		{"baseline", "Capabilities", true, "no capabilities added beyond the default set"},
+		{"baseline", "Seccomp", true, "seccompProfile not Unconfined"},
+		{"restricted", "Volume types", true, "only persistentVolumeClaim, emptyDir and similar volumes"},
+		{"restricted", "Privilege escalation", security.DisallowEscalation, "allowPrivilegeEscalation: false"},
+		{"restricted", "Running as non-root", security.RunAsNonRoot, "runAsNonRoot: true"},
+		{"restricted", "Seccomp profile", security.SeccompRuntimeDefault, "seccompProfile RuntimeDefault or Localhost"},
+		{"restricted", "Capabilities", security.DropAllCapabilities, "drop ALL, add at most NET_BIND_SERVICE"},
+	}
+	return checks
+}
+
+// Whether the chosen level enforces the check's rule
+func (security SecuritySpec) requires(check securityCheck) bool {
+	return slices.Index(podSecurityLevels, check.Level) <= slices.Index(podSecurityLevels, security.Level)
+}
+
+// Renders the pod-level securityContext
+func podSecurityString(security SecuritySpec) string {
+	if !security.RunAsNonRoot && !security.SeccompRuntimeDefault {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("      securityContext:\n")
+	if security.RunAsNonRoot {
+		sb.WriteString("        runAsNonRoot: true\n")
+	}
+	if security.SeccompRuntimeDefault {
+		sb.WriteString("        seccompProfile:\n          type: RuntimeDefault\n")
+	}
+	return sb.String()
+}
+
+// Renders the container-level securityContext
+func containerSecurityString(security SecuritySpec) string {
+	if !security.DisallowEscalation && !security.ReadOnlyRootFilesystem && !security.DropAllCapabilities {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("        securityContext:\n")
+	if security.DisallowEscalation {
+		sb.WriteString("          allowPrivilegeEscalation: false\n")
+	}
+	if security.ReadOnlyRootFilesystem {
+		sb.WriteString("          readOnlyRootFilesystem: true\n")
+	}
+	if security.DropAllCapabilities {
+		sb.WriteString("          capabilities:\n            drop:\n              - ALL\n")
+		if len(security.AddCapabilities) > 0 {
+			sb.WriteString("            add:\n")
+			for _, capability := range security.AddCapabilities {
+				sb.WriteString(fmt.Sprintf("              - %s\n", capability))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// Renders the container's /tmp mount when the root filesystem is read-only
+func tmpVolumeMountString(security SecuritySpec) string {
+	if !security.ReadOnlyRootFilesystem {
+		return ""
+	}
+	return "          - name: tmp\n            mountPath: /tmp\n"
+}
+
+// Renders the emptyDir volume backing /tmp
+func tmpVolumeString(security SecuritySpec) string {
+	if !security.ReadOnlyRootFilesystem {
+		return ""
+	}
+	return "      - name: tmp\n        emptyDir: {}\n"
+}
+
+// Renders the report of the Pod Security Standard rules
+func securityMarkdown(security SecuritySpec) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n## Pod Security (%s)\n\n", security.Level))
+	sb.WriteString("| Level | Rule | Requires | Status |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, check := range security.Checks {
+		status := "✓ satisfied"
+		switch {
+		case check.Satisfied:
+		case security.requires(check):
+			status = "✗ violated"
+		default:
+			status = "– not met, not enforced at this level"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", check.Level, check.Rule, check.Detail, status))
+	}
+	if security.RunAsNonRoot {
+		sb.WriteString("\nThe image must run as a non-root user (a numeric `USER` in its Dockerfile), or the kubelet refuses to start it.\n")
+	}
+	if security.ReadOnlyRootFilesystem {
+		sb.WriteString("The root filesystem is read-only; the app can write to `/tmp` and `/data`.\n")
+	}
+	return sb.String()
+}
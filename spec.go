@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Loads a ConfigSpec from a YAML spec file for headless runs
+func loadSpec(path string) (ConfigSpec, error) {
+	var config ConfigSpec
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading spec file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // Catch typos in field names
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("error parsing spec file %s: %v", path, err)
+	}
+
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("invalid spec file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Checks the values a spec file or the wizard must provide
+func (config *ConfigSpec) validate() error {
+	if config.AppName == "" {
+		return fmt.Errorf("appName is required")
+	}
+	if err := validateImage(config.ImageRepository, config.ImageTag, config.ImageDigest, config.ImagePullPolicy, config.ImagePullSecrets); err != nil {
+		return err
+	}
+	if err := validateEnv(config.Env, config.EnvRefs, config.EnvFrom); err != nil {
+		return err
+	}
+	if err := validateContainers(config.AppName, config.Containers); err != nil {
+		return err
+	}
+	if config.ExpectedLoad < 0 || config.DataSize < 0 || config.NetworkTraffic < 0 {
+		return fmt.Errorf("expectedLoad, dataSize and networkTraffic must not be negative")
+	}
+	if config.CPUTimePerRequestMs < 0 || config.LatencyTargetMs < 0 {
+		return fmt.Errorf("cpuTimePerRequestMs and latencyTargetMs must not be negative")
+	}
+	if config.LatencyTargetMs > 0 && config.CPUTimePerRequestMs == 0 {
+		return fmt.Errorf("latencyTargetMs needs cpuTimePerRequestMs to size compute")
+	}
+	if config.StartupSeconds < 0 {
+		return fmt.Errorf("startupSeconds must not be negative")
+	}
+	for _, path := range []string{config.LivenessPath, config.ReadinessPath} {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("probe path must start with /, got %q", path)
+		}
+	}
+	if err := validateRoute(config.Hostname, config.Path, config.Gateway); err != nil {
+		return err
+	}
+	if err := validateNamespaces(config.AllowedNamespaces); err != nil {
+		return fmt.Errorf("allowedNamespaces: %v", err)
+	}
+	if err := validateLabels(config.AllowedLabels); err != nil {
+		return fmt.Errorf("allowedLabels: %v", err)
+	}
+	if config.DataGrowthPercent < 0 || config.RetentionMonths < 0 || config.PlanningHorizonMonths < 0 {
+		return fmt.Errorf("dataGrowthPercent, retentionMonths and planningHorizonMonths must not be negative")
+	}
+	if config.IOPS < 0 || config.ThroughputMBps < 0 {
+		return fmt.Errorf("iops and throughputMBps must not be negative")
+	}
+	if _, ok := accessModes[config.AccessMode]; config.AccessMode != "" && !ok {
+		return fmt.Errorf("accessMode must be RWO, RWX or ROX, got %q", config.AccessMode)
+	}
+	if config.ConcurrencyModel != "" && !slices.Contains(concurrencyModels, config.ConcurrencyModel) {
+		return fmt.Errorf("concurrencyModel must be one of %v, got %q", concurrencyModels, config.ConcurrencyModel)
+	}
+	if _, ok := runtimeProfiles[config.Runtime]; config.Runtime != "" && !ok {
+		return fmt.Errorf("runtime must be one of %v, got %q", runtimeNames(), config.Runtime)
+	}
+	if config.PodSecurityLevel != "" && !slices.Contains(podSecurityLevels, config.PodSecurityLevel) {
+		return fmt.Errorf("podSecurityLevel must be one of %v, got %q", podSecurityLevels, config.PodSecurityLevel)
+	}
+	platform, err := loadPlatformConfig()
+	if err != nil {
+		return err
+	}
+	if levels := tierNames(platform.Tiers); !slices.Contains(levels, config.ImportanceLevel) {
+		return fmt.Errorf("importanceLevel must be one of %v, got %q", levels, config.ImportanceLevel)
+	}
+	return nil
+}
+
+// Prefix of the annotations recording the spec a manifest was generated from
+const specAnnotationPrefix = "tiny-workloads.io/"
+
+// specAnnotationField maps one ConfigSpec field to its annotation.
+type specAnnotationField struct {
+	key string
+	get func(config *ConfigSpec) string
+	set func(config *ConfigSpec, value string) error
+}
+
+// ConfigSpec fields recorded as Deployment annotations, so an imported
+// manifest yields its exact inputs instead of estimates
+var specAnnotationFields = []specAnnotationField{
+	intAnnotation("expected-load", func(config *ConfigSpec) *int { return &config.ExpectedLoad }),
+	intAnnotation("data-size", func(config *ConfigSpec) *int { return &config.DataSize }),
+	intAnnotation("network-traffic", func(config *ConfigSpec) *int { return &config.NetworkTraffic }),
+	floatAnnotation("cpu-time-per-request-ms", func(config *ConfigSpec) *float64 { return &config.CPUTimePerRequestMs }),
+	floatAnnotation("latency-target-ms", func(config *ConfigSpec) *float64 { return &config.LatencyTargetMs }),
+	stringAnnotation("concurrency-model", func(config *ConfigSpec) *string { return &config.ConcurrencyModel }),
+	intAnnotation("startup-seconds", func(config *ConfigSpec) *int { return &config.StartupSeconds }),
+	stringAnnotation("liveness-path", func(config *ConfigSpec) *string { return &config.LivenessPath }),
+	stringAnnotation("readiness-path", func(config *ConfigSpec) *string { return &config.ReadinessPath }),
+	stringAnnotation("hostname", func(config *ConfigSpec) *string { return &config.Hostname }),
+	stringAnnotation("path", func(config *ConfigSpec) *string { return &config.Path }),
+	stringAnnotation("gateway", func(config *ConfigSpec) *string { return &config.Gateway }),
+	{
+		key: "ports",
+		get: func(config *ConfigSpec) string { return formatPorts(config.Ports) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.Ports, err = parsePorts(value)
+			return err
+		},
+	},
+	{
+		key: "expose-metrics",
+		get: func(config *ConfigSpec) string {
+			if config.ExposeMetrics {
+				return "true"
+			}
+			return ""
+		},
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.ExposeMetrics, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	{
+		key: "allowed-namespaces",
+		get: func(config *ConfigSpec) string { return strings.Join(config.AllowedNamespaces, ",") },
+		set: func(config *ConfigSpec, value string) error {
+			config.AllowedNamespaces = splitList(value)
+			return nil
+		},
+	},
+	{
+		key: "allowed-labels",
+		get: func(config *ConfigSpec) string { return formatLabels(config.AllowedLabels) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.AllowedLabels, err = parseLabels(value)
+			return err
+		},
+	},
+	floatAnnotation("data-growth-percent", func(config *ConfigSpec) *float64 { return &config.DataGrowthPercent }),
+	intAnnotation("retention-months", func(config *ConfigSpec) *int { return &config.RetentionMonths }),
+	intAnnotation("planning-horizon-months", func(config *ConfigSpec) *int { return &config.PlanningHorizonMonths }),
+	intAnnotation("iops", func(config *ConfigSpec) *int { return &config.IOPS }),
+	intAnnotation("throughput-mbps", func(config *ConfigSpec) *int { return &config.ThroughputMBps }),
+	stringAnnotation("access-mode", func(config *ConfigSpec) *string { return &config.AccessMode }),
+	stringAnnotation("runtime", func(config *ConfigSpec) *string { return &config.Runtime }),
+	{
+		key: "env-refs",
+		get: func(config *ConfigSpec) string { return formatEnvRefs(config.EnvRefs) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.EnvRefs, err = parseEnvRefs(value)
+			return err
+		},
+	},
+	{
+		key: "env-from",
+		get: func(config *ConfigSpec) string { return formatEnvSources(config.EnvFrom) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.EnvFrom, err = parseEnvSources(value)
+			return err
+		},
+	},
+	{
+		key: "containers",
+		get: func(config *ConfigSpec) string { return formatContainers(config.Containers) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.Containers, err = parseContainers(value)
+			return err
+		},
+	},
+	{
+		key: "vertical-autoscaling",
+		get: func(config *ConfigSpec) string {
+			if config.VerticalAutoscaling {
+				return "true"
+			}
+			return ""
+		},
+		set: func(config *ConfigSpec, value string) (err error) {
+			config.VerticalAutoscaling, err = strconv.ParseBool(value)
+			return err
+		},
+	},
+	stringAnnotation("pod-security-level", func(config *ConfigSpec) *string { return &config.PodSecurityLevel }),
+	floatAnnotation("rps-per-core", func(config *ConfigSpec) *float64 { return &config.RPSPerCore }),
+	intAnnotation("measured-memory-mi", func(config *ConfigSpec) *int { return &config.MeasuredMemoryMi }),
+	stringAnnotation("importance-level", func(config *ConfigSpec) *string { return &config.ImportanceLevel }),
+}
+
+// Annotations written even when zero, so every manifest records the core inputs
+var coreSpecAnnotations = []string{"expected-load", "data-size", "network-traffic", "importance-level"}
+
+func intAnnotation(key string, target func(config *ConfigSpec) *int) specAnnotationField {
+	return specAnnotationField{
+		key: key,
+		get: func(config *ConfigSpec) string { return strconv.Itoa(*target(config)) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			*target(config), err = strconv.Atoi(value)
+			return err
+		},
+	}
+}
+
+func floatAnnotation(key string, target func(config *ConfigSpec) *float64) specAnnotationField {
+	return specAnnotationField{
+		key: key,
+		get: func(config *ConfigSpec) string { return strconv.FormatFloat(*target(config), 'f', -1, 64) },
+		set: func(config *ConfigSpec, value string) (err error) {
+			*target(config), err = strconv.ParseFloat(value, 64)
+			return err
+		},
+	}
+}
+
+func stringAnnotation(key string, target func(config *ConfigSpec) *string) specAnnotationField {
+	return specAnnotationField{
+		key: key,
+		get: func(config *ConfigSpec) string { return *target(config) },
+		set: func(config *ConfigSpec, value string) error {
+			*target(config) = value
+			return nil
+		},
+	}
+}
+
+// Renders the spec annotations as YAML lines indented for Deployment metadata
+func specAnnotations(config *ConfigSpec) string {
+	var lines []string
+	for _, field := range specAnnotationFields {
+		value := field.get(config)
+		if (value == "" || value == "0") && !slices.Contains(coreSpecAnnotations, field.key) {
+			continue // Unset optional inputs are left out, reading them back yields zero anyway
+		}
+		lines = append(lines, fmt.Sprintf("    %s%s: %q", specAnnotationPrefix, field.key, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Applies the spec annotations present on a manifest to config and reports
+// whether any were found
+func applySpecAnnotations(annotations map[string]string, config *ConfigSpec) (bool, error) {
+	found := false
+	for _, field := range specAnnotationFields {
+		value, ok := annotations[specAnnotationPrefix+field.key]
+		if !ok {
+			continue
+		}
+		if err := field.set(config, value); err != nil {
+			return found, fmt.Errorf("invalid annotation %s%s: %v", specAnnotationPrefix, field.key, err)
+		}
+		found = true
+	}
+	return found, nil
+}
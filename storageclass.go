@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Access modes by their short names, as accepted in the spec
+var accessModes = map[string]string{
+	"RWO": "ReadWriteOnce",
+	"RWX": "ReadWriteMany",
+	"ROX": "ReadOnlyMany",
+}
+
+// storageClassOffer is what one storage class of the cluster provides and
+// what it costs.
+type storageClassOffer struct {
+	Name              string   `yaml:"name"`
+	MaxIOPS           int      `yaml:"maxIOPS"`
+	MaxThroughputMBps int      `yaml:"maxThroughputMBps"`
+	MaxCapacityGi     int      `yaml:"maxCapacityGi"`
+	AccessModes       []string `yaml:"accessModes"` // Short names, RWO, RWX or ROX
+	CostPerGiMonth    float64  `yaml:"costPerGiMonth"`
+}
+
+// storageCatalog lists the storage classes the decider may choose from.
+type storageCatalog struct {
+	StorageClasses []storageClassOffer `yaml:"storageClasses"`
+}
+
+// Catalog used when the cluster does not define one, matching the classes
+// the decider has always picked between, plus a network file system class
+// for apps whose replicas share their data
+var defaultStorageCatalog = storageCatalog{
+	StorageClasses: []storageClassOffer{
+		{Name: "standard", MaxIOPS: 3000, MaxThroughputMBps: 125, MaxCapacityGi: 16384, AccessModes: []string{"RWO"}, CostPerGiMonth: 0.08},
+		{Name: "premium", MaxIOPS: 16000, MaxThroughputMBps: 1000, MaxCapacityGi: 65536, AccessModes: []string{"RWO"}, CostPerGiMonth: 0.17},
+		{Name: "shared", MaxIOPS: 1000, MaxThroughputMBps: 100, MaxCapacityGi: 65536, AccessModes: []string{"RWX", "ROX"}, CostPerGiMonth: 0.30},
+	},
+}
+
+// Path of the user-defined storage class catalog
+func storageCatalogPath() string {
+	return filepath.Join(stateDir, "storage-classes.yaml")
+}
+
+// Loads the storage class catalog, falling back to the default one
+func loadStorageCatalog() (storageCatalog, error) {
+	data, err := os.ReadFile(storageCatalogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultStorageCatalog, nil
+	}
+	if err != nil {
+		return storageCatalog{}, fmt.Errorf("error reading storage catalog: %v", err)
+	}
+
+	var catalog storageCatalog
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&catalog); err != nil {
+		return storageCatalog{}, fmt.Errorf("error parsing %s: %v", storageCatalogPath(), err)
+	}
+	if len(catalog.StorageClasses) == 0 {
+		return storageCatalog{}, fmt.Errorf("%s defines no storageClasses", storageCatalogPath())
+	}
+	for _, offer := range catalog.StorageClasses {
+		for _, mode := range offer.AccessModes {
+			if _, ok := accessModes[mode]; !ok {
+				return storageCatalog{}, fmt.Errorf("storage class %s: unknown access mode %q, use RWO, RWX or ROX", offer.Name, mode)
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// Whether the spec states any storage performance or access requirement
+func (config *ConfigSpec) hasStorageRequirements() bool {
+	return config.IOPS > 0 || config.ThroughputMBps > 0 || config.AccessMode != ""
+}
+
+// Access mode requested by the spec, ReadWriteOnce if unset
+func (config *ConfigSpec) accessMode() string {
+	if config.AccessMode == "" {
+		return "RWO"
+	}
+	return config.AccessMode
+}
+
+// Selects the cheapest storage class of the catalog that meets the IOPS,
+// throughput and access mode requirements at the given capacity, returning
+// it with its monthly cost
+func (config *ConfigSpec) selectStorageClass(catalog storageCatalog, capacityGi float64) (storageClassOffer, float64, error) {
+	var best storageClassOffer
+	bestCost := -1.0
+	var rejected []string
+	for _, offer := range catalog.StorageClasses {
+		var reasons []string
+		if config.IOPS > offer.MaxIOPS {
+			reasons = append(reasons, fmt.Sprintf("%d IOPS max", offer.MaxIOPS))
+		}
+		if config.ThroughputMBps > offer.MaxThroughputMBps {
+			reasons = append(reasons, fmt.Sprintf("%d MB/s max", offer.MaxThroughputMBps))
+		}
+		if offer.MaxCapacityGi > 0 && capacityGi > float64(offer.MaxCapacityGi) {
+			reasons = append(reasons, fmt.Sprintf("%dGi max", offer.MaxCapacityGi))
+		}
+		if !slices.Contains(offer.AccessModes, config.accessMode()) {
+			reasons = append(reasons, fmt.Sprintf("no %s", config.accessMode()))
+		}
+		if len(reasons) > 0 {
+			rejected = append(rejected, fmt.Sprintf("%s (%s)", offer.Name, strings.Join(reasons, ", ")))
+			continue
+		}
+
+		cost := offer.CostPerGiMonth * capacityGi
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = offer, cost
+		}
+	}
+	if bestCost < 0 {
+		return storageClassOffer{}, 0, fmt.Errorf("no storage class meets %s at %.0fGi: %s",
+			storageRequirements(config.IOPS, config.ThroughputMBps, config.accessMode()), capacityGi, strings.Join(rejected, "; "))
+	}
+	return best, bestCost, nil
+}
+
+// Describes the storage requirements that were set, e.g. "5000 IOPS and RWO"
+func storageRequirements(iops, throughputMBps int, accessMode string) string {
+	var requirements []string
+	if iops > 0 {
+		requirements = append(requirements, fmt.Sprintf("%d IOPS", iops))
+	}
+	if throughputMBps > 0 {
+		requirements = append(requirements, fmt.Sprintf("%d MB/s", throughputMBps))
+	}
+	requirements = append(requirements, accessMode)
+	if len(requirements) == 1 {
+		return requirements[0]
+	}
+	return strings.Join(requirements[:len(requirements)-1], ", ") + " and " + requirements[len(requirements)-1]
+}
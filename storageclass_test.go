@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSelectStorageClass(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     ConfigSpec
+		capacityGi float64
+		want       string
+		wantCost   float64
+		wantErr    []string // Parts of the error, none if empty
+	}{
+		{
+			name:       "no requirements picks the cheapest",
+			capacityGi: 100,
+			want:       "standard",
+			wantCost:   8,
+		},
+		{
+			name:       "IOPS beyond standard",
+			config:     ConfigSpec{IOPS: 5000},
+			capacityGi: 100,
+			want:       "premium",
+			wantCost:   17,
+		},
+		{
+			name:       "throughput beyond standard",
+			config:     ConfigSpec{ThroughputMBps: 500},
+			capacityGi: 10,
+			want:       "premium",
+			wantCost:   1.7,
+		},
+		{
+			name:       "shared access",
+			config:     ConfigSpec{AccessMode: "RWX"},
+			capacityGi: 10,
+			want:       "shared",
+			wantCost:   3,
+		},
+		{
+			name:       "capacity beyond standard",
+			capacityGi: 20000,
+			want:       "premium",
+			wantCost:   3400,
+		},
+		{
+			name:       "nothing fits lists every rejection",
+			config:     ConfigSpec{IOPS: 20000, AccessMode: "RWX"},
+			capacityGi: 10,
+			wantErr:    []string{"20000 IOPS and RWX", "standard (3000 IOPS max, no RWX)", "premium (16000 IOPS max, no RWX)", "shared (1000 IOPS max)"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			offer, cost, err := test.config.selectStorageClass(defaultStorageCatalog, test.capacityGi)
+			if len(test.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("selectStorageClass picked %s, want an error", offer.Name)
+				}
+				for _, part := range test.wantErr {
+					if !strings.Contains(err.Error(), part) {
+						t.Errorf("error %q does not mention %q", err, part)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectStorageClass: %v", err)
+			}
+			if offer.Name != test.want {
+				t.Errorf("selectStorageClass picked %s, want %s", offer.Name, test.want)
+			}
+			if math.Abs(cost-test.wantCost) > 1e-9 {
+				t.Errorf("cost = %.2f, want %.2f", cost, test.wantCost)
+			}
+		})
+	}
+}
+
+func TestDecideStorageReplicas(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name        string
+		config      ConfigSpec
+		wantClass   string
+		wantMode    string
+		wantWarning bool
+	}{
+		{"single replica", ConfigSpec{ImportanceLevel: "low"}, "standard", "ReadWriteOnce", false},
+		{"replicas keep the decided class", ConfigSpec{ImportanceLevel: "high"}, "standard", "ReadWriteOnce", true},
+		{"replicas keep the premium rule", ConfigSpec{ImportanceLevel: "high", DataSize: 300}, "premium", "ReadWriteOnce", true},
+		{"explicit ReadWriteOnce only warns", ConfigSpec{ImportanceLevel: "high", AccessMode: "RWO"}, "standard", "ReadWriteOnce", true},
+		{"shared claim is opt-in", ConfigSpec{ImportanceLevel: "high", AccessMode: "RWX"}, "shared", "ReadWriteMany", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := make(chan TimedResult, 1)
+			test.config.decideStorage(results)
+			result := <-results
+			if result.Error != nil {
+				t.Fatalf("decideStorage: %v", result.Error)
+			}
+			storage := result.StorageSpec
+			if storage.Class != test.wantClass || storage.AccessMode != test.wantMode {
+				t.Errorf("claim = %s %s, want %s %s", storage.Class, storage.AccessMode, test.wantClass, test.wantMode)
+			}
+			if (storage.Warning != "") != test.wantWarning {
+				t.Errorf("warning = %q, want one %v", storage.Warning, test.wantWarning)
+			}
+		})
+	}
+}
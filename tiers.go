@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+)
+
+// importanceTier is an importance level and what it changes about the
+// decisions. The platform config can replace the built-in tiers.
+type importanceTier struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"` // Shown next to the tier in the wizard
+
+	// Sizing, applied on top of the load-based decisions
+	CPUMultiplier    float64 `yaml:"cpuMultiplier"`    // Scales the CPU decided from load, 1 if unset
+	ExtraCPU         float64 `yaml:"extraCPU"`         // Cores added after scaling
+	MemoryMultiplier float64 `yaml:"memoryMultiplier"` // Scales the memory decided from load and data, 1 if unset
+	Memory           string  `yaml:"memory"`           // Replaces the baseline memory, e.g. "1Gi"
+	MinReplicas      int     `yaml:"minReplicas"`
+
+	// Storage overrides
+	StorageCapacityGi int    `yaml:"storageCapacityGi"`
+	StorageClass      string `yaml:"storageClass"` // Unless performance requirements select one from the catalog
+
+	HTTPS bool `yaml:"https"` // Serve HTTPS on 443 next to the app's ports
+
+	Probes *probeTiming `yaml:"probes"` // Health check timing, every 10s with a 3s timeout if unset
+
+	// Scheduling, merged into the availability and priorityClasses settings
+	Availability  *availabilityPolicy  `yaml:"availability"`
+	PriorityClass *priorityClassPolicy `yaml:"priorityClass"`
+
+	VerticalAutoscaling *verticalAutoscalingPolicy `yaml:"verticalAutoscaling"` // Merged into the verticalAutoscaling settings
+}
+
+// Tiers used when the platform config defines none
+var defaultTiers = []importanceTier{
+	{
+		Name:              "high",
+		Description:       "Business critical: more CPU and memory, HTTPS, spread for availability",
+		ExtraCPU:          0.25,
+		Memory:            "1Gi",
+		StorageCapacityGi: 20,
+		HTTPS:             true,
+		// Taken out of rotation quickly
+		Probes: &probeTiming{PeriodSeconds: 5, TimeoutSeconds: 2, FailureThreshold: 3},
+	},
+	{
+		Name:        "medium",
+		Description: "Regular service sized from its load",
+		Probes:      &probeTiming{PeriodSeconds: 10, TimeoutSeconds: 3, FailureThreshold: 3},
+	},
+	{
+		Name:        "low",
+		Description: "Best effort, never preempts other apps",
+		// Given time before being restarted
+		Probes: &probeTiming{PeriodSeconds: 20, TimeoutSeconds: 5, FailureThreshold: 5},
+	},
+}
+
+// Checks the tier's values
+func (tier importanceTier) validate() error {
+	if tier.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if tier.CPUMultiplier < 0 || tier.MemoryMultiplier < 0 || tier.ExtraCPU < 0 {
+		return fmt.Errorf("cpuMultiplier, memoryMultiplier and extraCPU must not be negative")
+	}
+	if tier.MinReplicas < 0 || tier.StorageCapacityGi < 0 {
+		return fmt.Errorf("minReplicas and storageCapacityGi must not be negative")
+	}
+	if tier.Memory != "" {
+		if _, err := parseMemoryMi(tier.Memory); err != nil {
+			return err
+		}
+	}
+	if tier.Probes != nil {
+		if err := tier.Probes.validate(); err != nil {
+			return fmt.Errorf("probes: %v", err)
+		}
+	}
+	return nil
+}
+
+// Scales CPU decided from load by the tier
+func (tier importanceTier) scaleCPU(cpu float64) float64 {
+	if tier.CPUMultiplier > 0 {
+		cpu *= tier.CPUMultiplier
+	}
+	return cpu + tier.ExtraCPU
+}
+
+// Scales memory in Mi by the tier
+func (tier importanceTier) scaleMemory(memoryMi float64) float64 {
+	if tier.MemoryMultiplier > 0 {
+		memoryMi *= tier.MemoryMultiplier
+	}
+	return memoryMi
+}
+
+// Names of the tiers in the order they are offered
+func tierNames(tiers []importanceTier) []string {
+	names := make([]string, len(tiers))
+	for i, tier := range tiers {
+		names[i] = tier.Name
+	}
+	return names
+}
+
+// Looks up the spec's importance tier in the platform config. Unknown tiers
+// change nothing; validate rejects them where the spec is entered.
+func (config *ConfigSpec) tier() (importanceTier, error) {
+	platform, err := loadPlatformConfig()
+	if err != nil {
+		return importanceTier{}, err
+	}
+	for _, tier := range platform.Tiers {
+		if tier.Name == config.ImportanceLevel {
+			return tier, nil
+		}
+	}
+	return importanceTier{Name: config.ImportanceLevel}, nil
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+)
+
+// wizardField is one text input of the wizard: how it is shown and how its
+// value is applied to the ConfigSpec.
+type wizardField struct {
+	label       string
+	placeholder string
+	charLimit   int
+	validate    func(value string) error                     // Checked while typing, nil accepts anything
+	apply       func(config *ConfigSpec, value string) error // Stores the value, erroring on invalid input
+}
+
+// Concurrency models understood by the compute decider
+var concurrencyModels = []string{"threaded", "event-loop"}
+
+// Text inputs of the wizard, in the order they are shown
+var wizardFields = []wizardField{
+	{
+		label:       "App Name:",
+		placeholder: "e.g. my-web-app",
+		charLimit:   30,
+		apply: func(config *ConfigSpec, value string) error {
+			config.AppName = value
+			return nil
+		},
+	},
+	{
+		label:       "Image Repository:",
+		placeholder: "optional, e.g. ghcr.io/acme/shop",
+		charLimit:   200,
+		apply: func(config *ConfigSpec, value string) error {
+			if value != "" && !imageRepositoryPattern.MatchString(value) {
+				return fmt.Errorf("invalid image repository: %q", value)
+			}
+			config.ImageRepository = value
+			return nil
+		},
+	},
+	{
+		label:       "Image Tag or Digest:",
+		placeholder: "e.g. 1.4.2, sha256:... or 1.4.2@sha256:...",
+		charLimit:   200,
+		apply: func(config *ConfigSpec, value string) error {
+			config.ImageTag, config.ImageDigest = parseTagOrDigest(value)
+			return validateImage(config.ImageRepository, config.ImageTag, config.ImageDigest, "", nil)
+		},
+	},
+	{
+		label:       "Image Pull Policy:",
+		placeholder: "optional, " + strings.Join(imagePullPolicies, ", "),
+		charLimit:   12,
+		apply: func(config *ConfigSpec, value string) error {
+			if value != "" && !slices.Contains(imagePullPolicies, value) {
+				return fmt.Errorf("invalid image pull policy: must be one of %v", imagePullPolicies)
+			}
+			config.ImagePullPolicy = value
+			return nil
+		},
+	},
+	{
+		label:       "Image Pull Secrets:",
+		placeholder: "optional, e.g. ghcr-pull,mirror-pull",
+		charLimit:   200,
+		apply: func(config *ConfigSpec, value string) error {
+			config.ImagePullSecrets = splitList(value)
+			return validateImage("", "", "", "", config.ImagePullSecrets)
+		},
+	},
+	{
+		label:       "Expected Load (RPS):",
+		placeholder: "e.g. 500",
+		charLimit:   6,
+		validate:    validateNumber,
+		apply:       intField("expected load", func(config *ConfigSpec) *int { return &config.ExpectedLoad }),
+	},
+	{
+		label:       "Data Size (MB):",
+		placeholder: "e.g. 100",
+		charLimit:   6,
+		validate:    validateNumber,
+		apply:       intField("data size", func(config *ConfigSpec) *int { return &config.DataSize }),
+	},
+	{
+		label:       "Network Traffic (Mbps):",
+		placeholder: "e.g. 75",
+		charLimit:   6,
+		validate:    validateNumber,
+		apply:       intField("network traffic", func(config *ConfigSpec) *int { return &config.NetworkTraffic }),
+	},
+	{
+		label:       "Service Ports:",
+		placeholder: "optional, e.g. grpc:9000/TCP/grpc",
+		charLimit:   200,
+		apply: func(config *ConfigSpec, value string) (err error) {
+			config.Ports, err = parsePorts(value)
+			if err != nil {
+				return fmt.Errorf("invalid service ports: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Expose Metrics:",
+		placeholder: "optional, yes or no",
+		charLimit:   3,
+		apply: func(config *ConfigSpec, value string) error {
+			switch strings.ToLower(value) {
+			case "", "n", "no":
+				config.ExposeMetrics = false
+			case "y", "yes":
+				config.ExposeMetrics = true
+			default:
+				return fmt.Errorf("invalid expose metrics: answer yes or no")
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Startup Time (s):",
+		placeholder: "optional, assumed from runtime",
+		charLimit:   4,
+		validate:    validateNumber,
+		apply:       optionalIntField("startup time", func(config *ConfigSpec) *int { return &config.StartupSeconds }),
+	},
+	{
+		label:       "Liveness Path:",
+		placeholder: "optional, e.g. /healthz",
+		charLimit:   60,
+		apply:       probePathField("liveness path", func(config *ConfigSpec) *string { return &config.LivenessPath }),
+	},
+	{
+		label:       "Readiness Path:",
+		placeholder: "optional, e.g. /readyz",
+		charLimit:   60,
+		apply:       probePathField("readiness path", func(config *ConfigSpec) *string { return &config.ReadinessPath }),
+	},
+	{
+		label:       "Public Hostname:",
+		placeholder: "optional, e.g. shop.example.com",
+		charLimit:   100,
+		apply: func(config *ConfigSpec, value string) error {
+			config.Hostname = value
+			return validateRoute(config.Hostname, "", "")
+		},
+	},
+	{
+		label:       "Public Path:",
+		placeholder: "optional, default /",
+		charLimit:   60,
+		apply: func(config *ConfigSpec, value string) error {
+			config.Path = value
+			return validateRoute(config.Hostname, config.Path, "")
+		},
+	},
+	{
+		label:       "Gateway (ns/name):",
+		placeholder: "optional, uses an Ingress if empty",
+		charLimit:   80,
+		apply: func(config *ConfigSpec, value string) error {
+			config.Gateway = value
+			return validateRoute(config.Hostname, config.Path, config.Gateway)
+		},
+	},
+	{
+		label:       "Allowed Namespaces:",
+		placeholder: "optional, e.g. web,payments",
+		charLimit:   120,
+		apply: func(config *ConfigSpec, value string) error {
+			config.AllowedNamespaces = splitList(value)
+			return validateNamespaces(config.AllowedNamespaces)
+		},
+	},
+	{
+		label:       "Allowed Pod Labels:",
+		placeholder: "optional, e.g. role=frontend",
+		charLimit:   120,
+		apply: func(config *ConfigSpec, value string) (err error) {
+			config.AllowedLabels, err = parseLabels(value)
+			if err != nil {
+				return fmt.Errorf("invalid allowed pod labels: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Data Growth (%/month):",
+		placeholder: "optional, e.g. 8",
+		charLimit:   6,
+		validate:    validateDecimal,
+		apply:       optionalFloatField("data growth", func(config *ConfigSpec) *float64 { return &config.DataGrowthPercent }),
+	},
+	{
+		label:       "Retention (months):",
+		placeholder: "optional, e.g. 24",
+		charLimit:   4,
+		validate:    validateNumber,
+		apply:       optionalIntField("retention", func(config *ConfigSpec) *int { return &config.RetentionMonths }),
+	},
+	{
+		label:       "Plan Horizon (months):",
+		placeholder: "optional, default 12",
+		charLimit:   4,
+		validate:    validateNumber,
+		apply:       optionalIntField("planning horizon", func(config *ConfigSpec) *int { return &config.PlanningHorizonMonths }),
+	},
+	{
+		label:       "Storage IOPS:",
+		placeholder: "optional, e.g. 5000",
+		charLimit:   7,
+		validate:    validateNumber,
+		apply:       optionalIntField("IOPS", func(config *ConfigSpec) *int { return &config.IOPS }),
+	},
+	{
+		label:       "Throughput (MB/s):",
+		placeholder: "optional, e.g. 250",
+		charLimit:   6,
+		validate:    validateNumber,
+		apply:       optionalIntField("throughput", func(config *ConfigSpec) *int { return &config.ThroughputMBps }),
+	},
+	{
+		label:       "Access Mode:",
+		placeholder: "optional, RWO, RWX or ROX",
+		charLimit:   3,
+		apply: func(config *ConfigSpec, value string) error {
+			value = strings.ToUpper(value)
+			if _, ok := accessModes[value]; value != "" && !ok {
+				return fmt.Errorf("invalid access mode: must be RWO, RWX or ROX")
+			}
+			config.AccessMode = value
+			return nil
+		},
+	},
+	{
+		label:       "CPU per Request (ms):",
+		placeholder: "optional, e.g. 4.5",
+		charLimit:   8,
+		validate:    validateDecimal,
+		apply:       optionalFloatField("CPU time per request", func(config *ConfigSpec) *float64 { return &config.CPUTimePerRequestMs }),
+	},
+	{
+		label:       "p99 Latency Target (ms):",
+		placeholder: "optional, e.g. 200",
+		charLimit:   8,
+		validate:    validateDecimal,
+		apply:       optionalFloatField("latency target", func(config *ConfigSpec) *float64 { return &config.LatencyTargetMs }),
+	},
+	{
+		label:       "Concurrency Model:",
+		placeholder: strings.Join(concurrencyModels, " or "),
+		charLimit:   12,
+		apply: func(config *ConfigSpec, value string) error {
+			if value != "" && !slices.Contains(concurrencyModels, value) {
+				return fmt.Errorf("invalid concurrency model: must be one of %v", concurrencyModels)
+			}
+			config.ConcurrencyModel = value
+			return nil
+		},
+	},
+	{
+		label:       "Runtime:",
+		placeholder: "optional, " + strings.Join(runtimeNames(), ", "),
+		charLimit:   10,
+		apply: func(config *ConfigSpec, value string) error {
+			if _, ok := runtimeProfiles[value]; value != "" && !ok {
+				return fmt.Errorf("invalid runtime: must be one of %v", runtimeNames())
+			}
+			config.Runtime = value
+			return nil
+		},
+	},
+	{
+		label:       "Env Vars:",
+		placeholder: "optional, e.g. LOG_LEVEL=info,REGION=eu",
+		charLimit:   500,
+		apply: func(config *ConfigSpec, value string) (err error) {
+			config.Env, err = parseEnv(value)
+			if err != nil {
+				return fmt.Errorf("invalid env vars: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Env From Keys:",
+		placeholder: "optional, e.g. DB_PASSWORD=secret:db/password",
+		charLimit:   500,
+		apply: func(config *ConfigSpec, value string) (err error) {
+			config.EnvRefs, err = parseEnvRefs(value)
+			if err != nil {
+				return fmt.Errorf("invalid env references: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Env From Objects:",
+		placeholder: "optional, e.g. secret:shop-secrets,configMap:shared:SHARED_",
+		charLimit:   500,
+		apply: func(config *ConfigSpec, value string) (err error) {
+			config.EnvFrom, err = parseEnvSources(value)
+			if err != nil {
+				return fmt.Errorf("invalid env sources: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Extra Containers:",
+		placeholder: "optional, e.g. proxy=sidecar:envoy,migrate=init::ghcr.io/acme/migrate:1.0",
+		charLimit:   500,
+		apply: func(config *ConfigSpec, value string) (err error) {
+			config.Containers, err = parseContainers(value)
+			if err != nil {
+				return fmt.Errorf("invalid extra containers: %v", err)
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Vertical Autoscaling:",
+		placeholder: "optional, yes or no",
+		charLimit:   3,
+		apply: func(config *ConfigSpec, value string) error {
+			switch strings.ToLower(value) {
+			case "", "n", "no":
+				config.VerticalAutoscaling = false
+			case "y", "yes":
+				config.VerticalAutoscaling = true
+			default:
+				return fmt.Errorf("invalid vertical autoscaling: answer yes or no")
+			}
+			return nil
+		},
+	},
+	{
+		label:       "Pod Security Level:",
+		placeholder: "optional, restricted if unset, baseline or privileged",
+		charLimit:   10,
+		apply: func(config *ConfigSpec, value string) error {
+			if value != "" && !slices.Contains(podSecurityLevels, value) {
+				return fmt.Errorf("invalid pod security level: must be one of %v", podSecurityLevels)
+			}
+			config.PodSecurityLevel = value
+			return nil
+		},
+	},
+}
+
+// Creates the text inputs for the wizard fields, focusing the first one
+func newWizardInputs() []textinput.Model {
+	inputs := make([]textinput.Model, len(wizardFields))
+	for i, field := range wizardFields {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = field.placeholder
+		inputs[i].PlaceholderStyle = blurredPromptStyle
+		inputs[i].CharLimit = field.charLimit
+		inputs[i].Prompt = "> "
+		inputs[i].Validate = field.validate
+		inputs[i].TextStyle = blurredInputStyle
+		inputs[i].PromptStyle = blurredPromptStyle
+	}
+
+	inputs[0].Focus()
+	inputs[0].TextStyle = focusedInputStyle    // Apply style to the text itself
+	inputs[0].PromptStyle = focusedPromptStyle // Apply style to the prompt
+	return inputs
+}
+
+// Applies every wizard input to config, stopping at the first invalid one
+func (m model) applyInputs(config *ConfigSpec) error {
+	for i, field := range wizardFields {
+		if err := field.apply(config, strings.TrimSpace(m.inputs[i].Value())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Applies the wizard inputs that are valid so far, skipping the others
+func (m model) applyValidInputs(config *ConfigSpec) {
+	for i, field := range wizardFields {
+		field.apply(config, strings.TrimSpace(m.inputs[i].Value()))
+	}
+}
+
+func validateNumber(s string) error {
+	_, err := strconv.Atoi(s)
+	if s != "" && err != nil {
+		return fmt.Errorf("must be a number")
+	}
+	return nil
+}
+
+func validateDecimal(s string) error {
+	_, err := strconv.ParseFloat(s, 64)
+	if s != "" && err != nil {
+		return fmt.Errorf("must be a number")
+	}
+	return nil
+}
+
+// Applies a required whole number
+func intField(name string, target func(config *ConfigSpec) *int) func(*ConfigSpec, string) error {
+	return func(config *ConfigSpec, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		*target(config) = number
+		return nil
+	}
+}
+
+// Applies an optional whole number, where empty means zero
+func optionalIntField(name string, target func(config *ConfigSpec) *int) func(*ConfigSpec, string) error {
+	apply := intField(name, target)
+	return func(config *ConfigSpec, value string) error {
+		if value == "" {
+			*target(config) = 0
+			return nil
+		}
+		return apply(config, value)
+	}
+}
+
+// Applies an optional probe path, which has to be absolute
+func probePathField(name string, target func(config *ConfigSpec) *string) func(*ConfigSpec, string) error {
+	return func(config *ConfigSpec, value string) error {
+		if value != "" && !strings.HasPrefix(value, "/") {
+			return fmt.Errorf("invalid %s: must start with /", name)
+		}
+		*target(config) = value
+		return nil
+	}
+}
+
+// Applies an optional decimal number, where empty means zero
+func optionalFloatField(name string, target func(config *ConfigSpec) *float64) func(*ConfigSpec, string) error {
+	return func(config *ConfigSpec, value string) error {
+		if value == "" {
+			*target(config) = 0
+			return nil
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		*target(config) = number
+		return nil
+	}
+}
+
+// Range of inputs that fits the terminal, always including the focused one
+func (m model) visibleInputs() (int, int) {
+	height := m.height
+	if height == 0 {
+		height = previewDefaultHeight
+	}
+	rows := max(3, (height-14)/2) // Each input takes two lines, leave room for the rest of the form
+	if rows >= len(m.inputs) {
+		return 0, len(m.inputs)
+	}
+
+	focused := m.focused
+	if m.inputState != inputStateText {
+		focused = len(m.inputs) - 1
+	}
+	first := min(max(0, focused-rows/2), len(m.inputs)-rows)
+	return first, first + rows
+}